@@ -0,0 +1,84 @@
+package main
+
+import "strings"
+
+// expandBraceRules rewrites every rule whose pattern contains brace
+// alternatives into one rule per expansion, keeping the owners. It returns
+// the new content and the original line number of every new line.
+func expandBraceRules(content []byte) ([]byte, []int) {
+	var out []string
+	var lines []int
+	for i, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimSpace(line)
+		expanded := []string{line}
+		if trimmed != "" && trimmed[0] != '#' {
+			pattern, rest := splitRule(trimmed)
+			expanded = nil
+			for _, p := range expandBraces(pattern) {
+				expanded = append(expanded, p+rest)
+			}
+		}
+		for _, l := range expanded {
+			out = append(out, l)
+			lines = append(lines, i+1)
+		}
+	}
+	return []byte(strings.Join(out, "\n")), lines
+}
+
+// expandBraces expands brace alternatives in pattern like a shell does, e.g.
+// "*.{js,ts}" into "*.js" and "*.ts". Braces may be nested. Braces without
+// a comma at their top level and escaped braces are left as they are.
+func expandBraces(pattern string) []string {
+	start, end, alternatives := findBraces(pattern)
+	if start < 0 {
+		return []string{pattern}
+	}
+	var expanded []string
+	for _, alternative := range alternatives {
+		expanded = append(expanded, expandBraces(pattern[:start]+alternative+pattern[end+1:])...)
+	}
+	return expanded
+}
+
+// findBraces finds the first unescaped brace group with a comma at its top
+// level, returning the positions of its braces and its alternatives.
+func findBraces(pattern string) (int, int, []string) {
+	for start := 0; start < len(pattern); start++ {
+		switch pattern[start] {
+		case '\\':
+			start++
+			continue
+		case '{':
+		default:
+			continue
+		}
+
+		depth, last := 0, start+1
+		var alternatives []string
+	group:
+		for i := start + 1; i < len(pattern); i++ {
+			switch pattern[i] {
+			case '\\':
+				i++
+			case '{':
+				depth++
+			case ',':
+				if depth == 0 {
+					alternatives = append(alternatives, pattern[last:i])
+					last = i + 1
+				}
+			case '}':
+				if depth > 0 {
+					depth--
+					continue
+				}
+				if len(alternatives) == 0 {
+					break group
+				}
+				return start, i, append(alternatives, pattern[last:i])
+			}
+		}
+	}
+	return -1, -1, nil
+}
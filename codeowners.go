@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"codeownerreport/report"
+
+	"github.com/samber/lo"
+)
+
+// writeCodeowners writes CODEOWNERS rules reproducing the ownership of the
+// reported files. A directory is collapsed into a single rule if its reported
+// files and all of its tracked files, as given by trackedOwners, have the same
+// owners, so the rule doesn't change the ownership of files that weren't
+// reported. Without trackedOwners, every file gets its own rule. Unowned files
+// get rules without owners. Patterns are relative to pathPrefix, like those
+// of the CODEOWNERS file below it.
+func writeCodeowners(w io.Writer, r report.Report, trackedOwners map[string][]string, pathPrefix string) error {
+	relative := func(owners map[string][]string) map[string][]string {
+		if pathPrefix == "" || owners == nil {
+			return owners
+		}
+		return lo.MapKeys(owners, func(_ []string, file string) string {
+			return strings.TrimPrefix(file, pathPrefix+"/")
+		})
+	}
+	fileOwners := relative(r.FileOwners())
+	trackedOwners = relative(trackedOwners)
+	tracked := sortedKeys(trackedOwners)
+	joined := func(owners []string) string {
+		owners = slices.Clone(owners)
+		slices.Sort(owners)
+		return strings.Join(owners, " ")
+	}
+	collapsible := func(dir, owners string) bool {
+		if trackedOwners == nil {
+			return false
+		}
+		i, _ := slices.BinarySearch(tracked, dir)
+		for _, file := range tracked[i:] {
+			if !strings.HasPrefix(file, dir) {
+				break
+			}
+			if joined(trackedOwners[file]) != owners {
+				return false
+			}
+		}
+		return true
+	}
+	for _, line := range codeownersRules("", sortedKeys(fileOwners), fileOwners, collapsible) {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// codeownersRules returns the rules for the sorted files below dir, which is
+// empty or ends in a slash. The owners of every file are sorted. A
+// subdirectory whose files all have the same owners becomes a single rule if
+// collapsible agrees for these owners, given space-separated.
+func codeownersRules(dir string, files []string, fileOwners map[string][]string, collapsible func(dir, owners string) bool) []string {
+	rule := func(pattern string, owners []string) string {
+		pattern = "/" + strings.ReplaceAll(pattern, " ", `\ `)
+		return strings.Join(append([]string{pattern}, owners...), " ")
+	}
+
+	var rules []string
+	for len(files) > 0 {
+		name, _, isDir := strings.Cut(strings.TrimPrefix(files[0], dir), "/")
+		if !isDir {
+			rules = append(rules, rule(files[0], fileOwners[files[0]]))
+			files = files[1:]
+			continue
+		}
+		sub := dir + name + "/"
+		n := len(files)
+		for i, file := range files {
+			if !strings.HasPrefix(file, sub) {
+				n = i
+				break
+			}
+		}
+		owners := lo.Uniq(lo.Map(files[:n], func(file string, _ int) string {
+			return strings.Join(fileOwners[file], " ")
+		}))
+		if len(owners) == 1 && collapsible(sub, owners[0]) {
+			rules = append(rules, rule(sub, fileOwners[files[0]]))
+		} else {
+			rules = append(rules, codeownersRules(sub, files[:n], fileOwners, collapsible)...)
+		}
+		files = files[n:]
+	}
+	return rules
+}
+
+// trackedOwners resolves the owners of the tracked files, expanding teams like
+// those of the reported files.
+func trackedOwners(tracked []string, resolver report.OwnerResolver, teamMembers teams) (map[string][]string, error) {
+	owners := map[string][]string{}
+	for _, file := range tracked {
+		o, err := resolver.Resolve(file)
+		if err != nil {
+			return nil, fmt.Errorf("resolving owners of %s: %w", file, err)
+		}
+		owners[file] = o
+	}
+	if teamMembers != nil {
+		teamMembers.expandOwners(owners)
+	}
+	return owners, nil
+}
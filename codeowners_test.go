@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"codeownerreport/report"
+)
+
+func TestWriteCodeowners(t *testing.T) {
+	r := report.New(map[string][]string{
+		"src/api/b.go": {"@gophers"},
+		"src/lib/c.go": {"@gophers"},
+		"docs/a.md":    {"@docs"},
+		"docs/b.md":    {"@docs"},
+	})
+	tests := []struct {
+		name    string
+		r       report.Report
+		tracked map[string][]string
+		prefix  string
+		want    string
+	}{
+		{
+			name: "whole directories share owners",
+			tracked: map[string][]string{
+				"src/api/b.go": {"@gophers"}, "src/lib/c.go": {"@gophers"}, "src/main.go": {"@gophers"},
+				"docs/a.md": {"@docs"}, "docs/b.md": {"@docs"}, "docs/c.md": {"@docs"},
+			},
+			want: "/docs/ @docs\n/src/ @gophers\n",
+		},
+		{
+			name: "other files in the directory",
+			tracked: map[string][]string{
+				"src/api/b.go": {"@gophers"}, "src/api/README.md": {},
+				"src/lib/c.go": {"@gophers"}, "src/lib/README.md": {"@docs"},
+				"docs/a.md": {"@docs"}, "docs/b.md": {"@docs"},
+			},
+			want: "/docs/ @docs\n/src/api/b.go @gophers\n/src/lib/c.go @gophers\n",
+		},
+		{
+			name:    "no tracked files",
+			tracked: nil,
+			want:    "/docs/a.md @docs\n/docs/b.md @docs\n/src/api/b.go @gophers\n/src/lib/c.go @gophers\n",
+		},
+		{
+			name: "path prefix",
+			r: report.New(map[string][]string{
+				"svc/b.txt": {"@svc"}, "svc/pkg/a.go": {"@svcpkg"},
+			}),
+			tracked: map[string][]string{
+				"svc/b.txt": {"@svc"}, "svc/pkg/a.go": {"@svcpkg"}, "svc/pkg/b.go": {"@svcpkg"},
+			},
+			prefix: "svc",
+			want:   "/b.txt @svc\n/pkg/ @svcpkg\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.r.Owners == nil {
+				tt.r = r
+			}
+			var b strings.Builder
+			if err := writeCodeowners(&b, tt.r, tt.tracked, tt.prefix); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"codeownerreport/report"
+
+	"github.com/hmarr/codeowners"
+	"github.com/samber/lo"
+)
+
+// codeownersDiff compares the ownership of the changed files under the
+// CODEOWNERS file of the base commit with that of the head commit. A base
+// without CODEOWNERS file counts as one without rules.
+func codeownersDiff(change report.Change, opts options) (report.Delta, error) {
+	if strings.Contains(opts.codeowners, "://") || filepath.IsAbs(opts.codeowners) {
+		return report.Delta{}, fmt.Errorf("CODEOWNERS location %s is not inside the repository", opts.codeowners)
+	}
+	path := filepath.ToSlash(filepath.Clean(opts.codeowners))
+
+	headContent, err := report.ReadCodeownersAt(change.HeadCommit, path)
+	if err != nil {
+		return report.Delta{}, err
+	}
+	var baseContent []byte
+	if change.BaseCommit != nil {
+		baseContent, err = report.ReadCodeownersAt(change.BaseCommit, path)
+		if err != nil && !errors.Is(err, report.ErrNoCodeowners) {
+			return report.Delta{}, err
+		}
+	}
+
+	reportFor := func(content []byte) (report.Report, error) {
+		_, sections, err := loadRuleset(content, opts)
+		if err != nil {
+			return report.Report{}, err
+		}
+		fileOwners := map[string][]string{}
+		for file := range change.Files {
+			_, rules, err := matchSections(sections, matchPath(file, opts))
+			if err != nil {
+				return report.Report{}, err
+			}
+			fileOwners[file] = lo.Uniq(lo.FlatMap(rules, func(rule *codeowners.Rule, _ int) []string {
+				return lo.Map(rule.Owners, func(owner codeowners.Owner, _ int) string { return owner.String() })
+			}))
+		}
+		return report.New(fileOwners), nil
+	}
+	before, err := reportFor(baseContent)
+	if err != nil {
+		return report.Delta{}, fmt.Errorf("base CODEOWNERS: %w", err)
+	}
+	after, err := reportFor(headContent)
+	if err != nil {
+		return report.Delta{}, fmt.Errorf("head CODEOWNERS: %w", err)
+	}
+	return report.Compare(before, after), nil
+}
+
+// writeCodeownersDiff lists the changed files whose owners differ between the
+// base and head CODEOWNERS files.
+func writeCodeownersDiff(w io.Writer, d report.Delta, changedFiles int) {
+	fmt.Fprintf(w, "CODEOWNERS changes affect %d of %d changed files.\n", len(d.Changed), changedFiles)
+	for _, c := range d.Changed {
+		fmt.Fprintf(w, "  %s: %s -> %s\n", c.File, formatOwners(c.Before), formatOwners(c.After))
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"codeownerreport/report"
+
+	"github.com/hmarr/codeowners"
+	"github.com/samber/lo"
+)
+
+// ruleChange is a CODEOWNERS rule that differs between two revisions. Before
+// or after is nil if the rule was added or removed.
+type ruleChange struct {
+	pattern       string
+	before, after *codeowners.Rule
+}
+
+// compareCodeowners loads the CODEOWNERS file from the trees of two revisions
+// and returns the rules that were added, removed or given different owners.
+// Rules are identified by their pattern, and within GitLab sections by
+// section and pattern. A pattern listed more than once is compared by its last
+// rule, the one that takes effect.
+func compareCodeowners(revA, revB string, opts options) ([]ruleChange, error) {
+	repo, err := report.OpenRepository(".")
+	if err != nil {
+		return nil, fmt.Errorf("opening repository: %w", err)
+	}
+	path := filepath.ToSlash(filepath.Clean(opts.codeowners))
+
+	rulesAt := func(rev string) (map[string]*codeowners.Rule, error) {
+		commit, err := report.ResolveCommit(repo, rev)
+		if err != nil {
+			return nil, err
+		}
+		content, err := report.ReadCodeownersAt(commit, path)
+		if err != nil {
+			return nil, err
+		}
+		_, sections, err := loadRuleset(content, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", rev, err)
+		}
+		rules := map[string]*codeowners.Rule{}
+		for _, s := range sections {
+			for i, rule := range s.ruleset {
+				key := rule.RawPattern()
+				if opts.gitlab {
+					key = "[" + s.name + "] " + key
+				}
+				rules[key] = &s.ruleset[i]
+			}
+		}
+		return rules, nil
+	}
+	before, err := rulesAt(revA)
+	if err != nil {
+		return nil, err
+	}
+	after, err := rulesAt(revB)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []ruleChange
+	for _, key := range lo.Uniq(append(lo.Keys(before), lo.Keys(after)...)) {
+		a, b := before[key], after[key]
+		if a != nil && b != nil && slices.Equal(ownerNames(a), ownerNames(b)) {
+			continue
+		}
+		changes = append(changes, ruleChange{pattern: key, before: a, after: b})
+	}
+	slices.SortFunc(changes, func(x, y ruleChange) int { return strings.Compare(x.pattern, y.pattern) })
+	return changes, nil
+}
+
+func ownerNames(rule *codeowners.Rule) []string {
+	return lo.Map(rule.Owners, func(owner codeowners.Owner, _ int) string { return owner.String() })
+}
+
+// writeRuleChanges lists added (+), removed (-) and modified (~) rules with
+// their line numbers in the respective revision.
+func writeRuleChanges(w io.Writer, revA, revB string, changes []ruleChange) {
+	if len(changes) == 0 {
+		fmt.Fprintf(w, "CODEOWNERS rules of %s and %s are the same.\n", revA, revB)
+		return
+	}
+	for _, c := range changes {
+		switch {
+		case c.before == nil:
+			fmt.Fprintf(w, "+ %s (%s:%d) %s\n", c.pattern, revB, c.after.LineNumber, formatOwners(ownerNames(c.after)))
+		case c.after == nil:
+			fmt.Fprintf(w, "- %s (%s:%d) %s\n", c.pattern, revA, c.before.LineNumber, formatOwners(ownerNames(c.before)))
+		default:
+			fmt.Fprintf(w, "~ %s (%s:%d -> %s:%d) %s -> %s\n", c.pattern, revA, c.before.LineNumber, revB, c.after.LineNumber,
+				formatOwners(ownerNames(c.before)), formatOwners(ownerNames(c.after)))
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"codeownerreport/report"
+)
+
+// writeCSVWide writes one row per file with its change type, the number of
+// owners and the owners joined by semicolons. Fields containing delimiters
+// are quoted.
+func writeCSVWide(w io.Writer, r report.Report) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"path", "change_type", "owner_count", "owners"}); err != nil {
+		return err
+	}
+	fileOwners := r.FileOwners()
+	for _, file := range sortedKeys(fileOwners) {
+		owners := fileOwners[file]
+		row := []string{file, string(r.Changes[file]), strconv.Itoa(len(owners)), strings.Join(owners, ";")}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeTSV writes one owner<TAB>file row per owned file and owner, and one row
+// with an empty owner per unowned file. TSV has no quoting, so files with tabs
+// or line breaks in their path are rejected.
+func writeTSV(w io.Writer, r report.Report, owners []string) error {
+	write := func(owner, file string) error {
+		if strings.ContainsAny(file, "\t\r\n") {
+			return fmt.Errorf("can't write %q as TSV: path contains a tab or line break", file)
+		}
+		_, err := fmt.Fprintf(w, "%s\t%s\n", owner, file)
+		return err
+	}
+	if _, err := fmt.Fprintln(w, "owner\tfile"); err != nil {
+		return err
+	}
+	for _, owner := range owners {
+		for _, file := range r.Owners[owner] {
+			if err := write(owner, file); err != nil {
+				return err
+			}
+		}
+	}
+	for _, file := range r.Unowned {
+		if err := write("", file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
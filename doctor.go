@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"codeownerreport/report"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// checkEnv verifies that a report can be generated: that the working directory
+// is a git repository, HEAD and the base resolve, and the CODEOWNERS file is
+// found and parses. It writes the result of every check to w and reports
+// whether all of them passed. Checks that depend on a failed one are skipped.
+func checkEnv(w io.Writer, opts options) bool {
+	ok := true
+	check := func(name string, err error, detail string) bool {
+		switch {
+		case err != nil:
+			ok = false
+			fmt.Fprintf(w, "[FAIL] %s: %v\n", name, err)
+		case detail != "":
+			fmt.Fprintf(w, "[ok]   %s: %s\n", name, detail)
+		default:
+			fmt.Fprintf(w, "[ok]   %s\n", name)
+		}
+		return err == nil
+	}
+	skip := func(name string) {
+		fmt.Fprintf(w, "[skip] %s\n", name)
+	}
+
+	repo, err := report.OpenRepository(".")
+	if check("Git repository", err, "") {
+		checkRevisions(repo, opts, check)
+	} else {
+		skip("HEAD")
+		skip("Base branch")
+	}
+
+	content, err := readCodeowners(opts)
+	if check("CODEOWNERS found", err, opts.codeowners) {
+		ruleset, _, err := loadRuleset(content, opts)
+		check("CODEOWNERS parses", err, fmt.Sprintf("%d rules", len(ruleset)))
+	} else {
+		skip("CODEOWNERS parses")
+	}
+	return ok
+}
+
+// checkRevisions checks that HEAD and the base resolve to commits, using the
+// same base selection as LoadChange.
+func checkRevisions(repo *git.Repository, opts options, check func(name string, err error, detail string) bool) {
+	head, err := repo.Head()
+	if err == nil {
+		_, err = repo.CommitObject(head.Hash())
+	}
+	if err == nil {
+		check("HEAD", nil, head.Name().Short())
+	} else {
+		check("HEAD", err, "")
+	}
+
+	base := opts.base
+	if base == "" && opts.useRemoteDefault {
+		base, _ = report.RemoteDefaultBranch(repo)
+	}
+	name, commit, err := report.ResolveBase(repo, base)
+	if err == nil {
+		check("Base branch", nil, fmt.Sprintf("%s (%s)", name, commit.Hash.String()[:7]))
+	} else {
+		check("Base branch", err, "")
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"codeownerreport/report"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitattributes"
+)
+
+const generatedAttribute = "linguist-generated"
+
+// loadGenerated reads the .gitattributes files of the working tree and returns
+// a function reporting whether a file is marked linguist-generated, either
+// set or with the value true.
+func loadGenerated() (func(file string) bool, error) {
+	repo, err := report.OpenRepository(".")
+	if err != nil {
+		return nil, fmt.Errorf("opening repository: %w", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	patterns, err := gitattributes.ReadPatterns(worktree.Filesystem, nil)
+	if err != nil {
+		return nil, fmt.Errorf("reading .gitattributes: %w", err)
+	}
+	matcher := gitattributes.NewMatcher(patterns)
+	return func(file string) bool {
+		attrs, _ := matcher.Match(strings.Split(file, "/"), []string{generatedAttribute})
+		attr, ok := attrs[generatedAttribute]
+		return ok && (attr.IsSet() || attr.IsValueSet() && attr.Value() == "true")
+	}, nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"codeownerreport/report"
+)
+
+// codeownersLocations are the paths GitHub looks for a CODEOWNERS file in, in
+// order of precedence.
+var codeownersLocations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+var pullRequestPattern = regexp.MustCompile(`^([\w.-]+)/([\w.-]+)#(\d+)$`)
+
+type pullRequest struct {
+	owner  string
+	repo   string
+	number int
+}
+
+func (pr pullRequest) String() string {
+	return fmt.Sprintf("%s/%s#%d", pr.owner, pr.repo, pr.number)
+}
+
+func parsePullRequest(value string) (pullRequest, error) {
+	m := pullRequestPattern.FindStringSubmatch(value)
+	if m == nil {
+		return pullRequest{}, fmt.Errorf("%q: expected owner/repo#number", value)
+	}
+	number, _ := strconv.Atoi(m[3])
+	return pullRequest{owner: m[1], repo: m[2], number: number}, nil
+}
+
+// errNotFound is returned by githubClient when the API responds with 404.
+var errNotFound = errors.New("not found")
+
+// githubClient is a minimal client for the GitHub REST API.
+type githubClient struct {
+	http    *http.Client
+	baseURL string
+	token   string
+}
+
+// newGitHubClient creates a client authenticated with GITHUB_TOKEN, if set.
+// GITHUB_API_URL overrides the API endpoint for GitHub Enterprise.
+func newGitHubClient() githubClient {
+	baseURL := os.Getenv("GITHUB_API_URL")
+	if baseURL == "" {
+		baseURL = "https://api.github.com"
+	}
+	return githubClient{
+		http:    &http.Client{Timeout: 30 * time.Second},
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   os.Getenv("GITHUB_TOKEN"),
+	}
+}
+
+func (c githubClient) get(path, accept string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("GET %s: %w", path, errNotFound)
+	default:
+		return nil, fmt.Errorf("GET %s: unexpected status %s", path, resp.Status)
+	}
+}
+
+func (c githubClient) getJSON(path string, v any) error {
+	body, err := c.get(path, "application/vnd.github+json")
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// baseRef returns the branch a pull request targets.
+func (c githubClient) baseRef(pr pullRequest) (string, error) {
+	var details struct {
+		Base struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+	}
+	if err := c.getJSON(fmt.Sprintf("/repos/%s/%s/pulls/%d", pr.owner, pr.repo, pr.number), &details); err != nil {
+		return "", err
+	}
+	return details.Base.Ref, nil
+}
+
+// changedFiles returns the files changed by a pull request and how they
+// changed. Renamed files are returned with both their previous and new path.
+func (c githubClient) changedFiles(pr pullRequest) (map[string]report.ChangeType, error) {
+	files := map[string]report.ChangeType{}
+	for page := 1; ; page++ {
+		var entries []struct {
+			Filename         string `json:"filename"`
+			PreviousFilename string `json:"previous_filename"`
+			Status           string `json:"status"`
+		}
+		path := fmt.Sprintf("/repos/%s/%s/pulls/%d/files?per_page=100&page=%d", pr.owner, pr.repo, pr.number, page)
+		if err := c.getJSON(path, &entries); err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			files[e.Filename] = githubChangeTypes[e.Status]
+			if e.PreviousFilename != "" {
+				files[e.PreviousFilename] = report.Renamed
+			}
+		}
+		if len(entries) < 100 {
+			return files, nil
+		}
+	}
+}
+
+// githubChangeTypes maps the file statuses of the GitHub API to change types.
+var githubChangeTypes = map[string]report.ChangeType{
+	"added":     report.Added,
+	"copied":    report.Added,
+	"removed":   report.Deleted,
+	"renamed":   report.Renamed,
+	"modified":  report.Modified,
+	"changed":   report.Modified,
+	"unchanged": report.Modified,
+}
+
+// codeowners returns the CODEOWNERS file of a repository at the given ref,
+// looking in the same locations GitHub does.
+func (c githubClient) codeowners(pr pullRequest, ref string) ([]byte, error) {
+	for _, location := range codeownersLocations {
+		path := fmt.Sprintf("/repos/%s/%s/contents/%s?ref=%s", pr.owner, pr.repo, location, url.QueryEscape(ref))
+		content, err := c.get(path, "application/vnd.github.raw")
+		if errors.Is(err, errNotFound) {
+			continue
+		}
+		return content, err
+	}
+	return nil, fmt.Errorf("%w in %s/%s at %s", report.ErrNoCodeowners, pr.owner, pr.repo, ref)
+}
+
+// loadPullRequest fetches the changed files of the pull request selected by
+// --github-pr and the CODEOWNERS file of its base branch. An explicit
+// --codeowners takes precedence over the fetched file.
+func loadPullRequest(opts options) (map[string]report.ChangeType, []byte, error) {
+	pr, err := parsePullRequest(opts.githubPR)
+	if err != nil {
+		return nil, nil, err
+	}
+	client := newGitHubClient()
+
+	files, err := client.changedFiles(pr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching changed files: %w", err)
+	}
+	slog.Info("Fetched pull request files.", "pr", pr, "files", len(files))
+
+	if isFlagSet("codeowners") {
+		content, err := report.ReadCodeowners(opts.codeowners)
+		return files, content, err
+	}
+
+	ref, err := client.baseRef(pr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching pull request: %w", err)
+	}
+	content, err := client.codeowners(pr, ref)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching CODEOWNERS: %w", err)
+	}
+	slog.Info("Fetched CODEOWNERS.", "ref", ref)
+	return files, content, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hmarr/codeowners"
+	"github.com/samber/lo"
+)
+
+// defaultSection names the rules of a GitLab CODEOWNERS file that precede the
+// first section header.
+const defaultSection = "(default)"
+
+// sectionHeader matches GitLab section headers like "[Database]",
+// "^[Optional docs]" or "[Backend][2] @backend-team", capturing the name and
+// the default owners.
+var sectionHeader = regexp.MustCompile(`^\^?\[([^\]]+)\](?:\[\d+\])?\s*([^#]*)`)
+
+// section is a GitLab CODEOWNERS section. Every section is matched on its own,
+// so a file can be owned by several of them.
+type section struct {
+	name    string
+	ruleset codeowners.Ruleset
+}
+
+// parseSections splits a GitLab CODEOWNERS file into its sections. Rules
+// without owners get the default owners of their section's header. Sections
+// with the same name are merged. Line numbers refer to the original file.
+func parseSections(content []byte, opts options) ([]section, error) {
+	lines := strings.Split(string(content), "\n")
+	var names []string
+	sectionLines := map[string][]string{}
+	name, defaults := defaultSection, ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if m := sectionHeader.FindStringSubmatch(trimmed); m != nil {
+			name, defaults = strings.TrimSpace(m[1]), strings.TrimSpace(m[2])
+			continue
+		}
+		if trimmed == "" || trimmed[0] == '#' {
+			continue
+		}
+		if _, ok := sectionLines[name]; !ok {
+			names = append(names, name)
+			sectionLines[name] = make([]string, len(lines))
+		}
+		if pattern, rest := splitRule(trimmed); strings.TrimSpace(strings.SplitN(rest, "#", 2)[0]) == "" && defaults != "" {
+			trimmed = pattern + " " + defaults
+		}
+		sectionLines[name][i] = trimmed
+	}
+
+	return mapErr(names, func(name string) (section, error) {
+		ruleset, err := parseRuleset([]byte(strings.Join(sectionLines[name], "\n")), opts)
+		return section{name: name, ruleset: ruleset}, err
+	})
+}
+
+// loadRuleset parses CODEOWNERS content into its sections and a ruleset
+// holding the rules of all of them. Without GitLab syntax, the whole file is a
+// single section.
+func loadRuleset(content []byte, opts options) (codeowners.Ruleset, []section, error) {
+	if !opts.gitlab {
+		ruleset, err := parseRuleset(content, opts)
+		return ruleset, []section{{name: defaultSection, ruleset: ruleset}}, err
+	}
+	sections, err := parseSections(content, opts)
+	return sectionsRuleset(sections), sections, err
+}
+
+// sectionsRuleset combines the rules of all sections into one ruleset, for
+// features that don't need to tell sections apart.
+func sectionsRuleset(sections []section) codeowners.Ruleset {
+	return lo.FlatMap(sections, func(s section, _ int) []codeowners.Rule { return s.ruleset })
+}
+
+// matchSections returns the rule matching file in each section, in section
+// order, leaving out sections without a match.
+func matchSections(sections []section, file string) ([]string, []*codeowners.Rule, error) {
+	var names []string
+	var rules []*codeowners.Rule
+	for _, s := range sections {
+		rule, err := s.ruleset.Match(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		if rule != nil {
+			names = append(names, s.name)
+			rules = append(rules, rule)
+		}
+	}
+	return names, rules, nil
+}
+
+// addSectionOwners records file under each of its owners in the sections it
+// matched, given the owners of the matching rule of every section.
+func addSectionOwners(sectionOwners map[string]map[string][]string, file string, names []string, owners [][]string) {
+	for i, name := range names {
+		if sectionOwners[name] == nil {
+			sectionOwners[name] = map[string][]string{}
+		}
+		for _, owner := range owners[i] {
+			sectionOwners[name][owner] = append(sectionOwners[name][owner], file)
+		}
+	}
+}
+
+func sortSectionFiles(sectionOwners map[string]map[string][]string) {
+	for _, owners := range sectionOwners {
+		for _, files := range owners {
+			slices.Sort(files)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"codeownerreport/report"
+)
+
+// runHistory writes the ownership history of the last opts.history commits to
+// stdout, matching all of them against the same CODEOWNERS file: the current
+// one, or the one at --codeowners-rev.
+func runHistory(opts options) error {
+	changes, err := report.LoadHistory(".", opts.history, opts.retries, opts.excludeMerges)
+	if err != nil {
+		return err
+	}
+	content, err := readCodeowners(opts)
+	if err != nil {
+		return err
+	}
+	_, sections, err := loadRuleset(content, opts)
+	if err != nil {
+		return err
+	}
+	history, err := ownershipHistory(changes, sections, opts)
+	if err != nil {
+		return err
+	}
+	return writeHistory(os.Stdout, history)
+}
+
+// ownershipHistory counts the files each owner was responsible for in every
+// change. With GitLab sections, a file counts for the owners of its matching
+// rule in every section.
+func ownershipHistory(changes []report.Change, sections []section, opts options) ([]report.CommitOwnership, error) {
+	resolver := newSectionsResolver(sections, opts, nil)
+	history := make([]report.CommitOwnership, 0, len(changes))
+	for _, change := range changes {
+		entry := report.CommitOwnership{
+			Commit: change.HeadCommit.Hash.String(),
+			Time:   change.HeadCommit.Committer.When,
+			Owners: map[string]int{},
+		}
+		for file := range change.Files {
+			owners, err := resolver.Resolve(file)
+			if err != nil {
+				return nil, err
+			}
+			if len(owners) == 0 {
+				entry.Unowned++
+				continue
+			}
+			for _, owner := range owners {
+				entry.Owners[owner]++
+			}
+		}
+		history = append(history, entry)
+	}
+	return history, nil
+}
+
+func writeHistory(w io.Writer, history []report.CommitOwnership) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(history)
+}
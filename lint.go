@@ -0,0 +1,215 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"codeownerreport/report"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/hmarr/codeowners"
+	"github.com/samber/lo"
+)
+
+// duplicate is a pattern that appears on more than one line of a CODEOWNERS
+// file. Only the rule on the last line ever matches.
+type duplicate struct {
+	pattern string
+	lines   []int
+	// owners are the owners of the last rule, which take effect.
+	owners []string
+}
+
+// duplicatePatterns finds the patterns that appear more than once in ruleset,
+// in the order of their first occurrence.
+func duplicatePatterns(ruleset codeowners.Ruleset) []duplicate {
+	var duplicates []duplicate
+	for pattern, rules := range lo.GroupBy(ruleset, func(rule codeowners.Rule) string { return rule.RawPattern() }) {
+		if len(rules) < 2 {
+			continue
+		}
+		duplicates = append(duplicates, duplicate{
+			pattern: pattern,
+			lines:   lo.Map(rules, func(rule codeowners.Rule, _ int) int { return rule.LineNumber }),
+			owners: lo.Map(rules[len(rules)-1].Owners, func(owner codeowners.Owner, _ int) string {
+				return owner.String()
+			}),
+		})
+	}
+	slices.SortFunc(duplicates, func(a, b duplicate) int { return cmp.Compare(a.lines[0], b.lines[0]) })
+	return duplicates
+}
+
+// lintSuggestions combines the CODEOWNERS analyses into edits: removing
+// duplicate patterns, rules that match no tracked file and rules that are
+// overridden by later ones for all of their files, and adding rules for the
+// directories of unowned changed files. Without tracked files, e.g. outside of
+// a repository, unused and overridden rules aren't looked for.
+func lintSuggestions(sections []section, tracked []string, unowned []string, opts options) []report.Suggestion {
+	var suggestions []report.Suggestion
+	for _, s := range sections {
+		name := ""
+		if opts.gitlab {
+			name = s.name
+		}
+		shadowed := map[int]bool{}
+		for _, d := range duplicatePatterns(s.ruleset) {
+			last := d.lines[len(d.lines)-1]
+			earlier := d.lines[:len(d.lines)-1]
+			for _, line := range earlier {
+				shadowed[line] = true
+			}
+			suggestions = append(suggestions, report.Suggestion{
+				Kind:    report.SuggestionDuplicate,
+				Action:  "remove",
+				Pattern: d.pattern,
+				Lines:   earlier,
+				Section: name,
+				Message: fmt.Sprintf("remove %s: %s is listed again on line %d, which takes effect", formatLines(earlier), d.pattern, last),
+			})
+		}
+		if tracked != nil {
+			suggestions = append(suggestions, ruleSuggestions(s.ruleset, tracked, shadowed, name, opts)...)
+		}
+	}
+
+	dirs := lo.CountValues(lo.Map(unowned, func(file string, _ int) string {
+		return path.Dir(strings.TrimPrefix(file, opts.pathPrefix+"/"))
+	}))
+	for _, dir := range sortedKeys(dirs) {
+		pattern := "/" + dir + "/"
+		message := fmt.Sprintf("add a rule for %s: %s changed there without an owner", pattern, pluralFiles(dirs[dir]))
+		if dir == "." {
+			pattern = "*"
+			message = fmt.Sprintf("add a catch-all rule *: %s changed in the root directory without an owner", pluralFiles(dirs[dir]))
+		}
+		suggestions = append(suggestions, report.Suggestion{
+			Kind:    report.SuggestionUnownedDir,
+			Action:  "add",
+			Pattern: pattern,
+			Message: message,
+		})
+	}
+	return suggestions
+}
+
+// ruleSuggestions suggests removing the rules of ruleset that match none of
+// the tracked files, or only files a later rule takes over. Rules whose line
+// is in skip are left out.
+func ruleSuggestions(ruleset codeowners.Ruleset, tracked []string, skip map[int]bool, section string, opts options) []report.Suggestion {
+	matched := map[int]int{}
+	effective := map[int]int{}
+	overriders := map[int]map[int]bool{}
+	for _, file := range tracked {
+		file = matchPath(file, opts)
+		var lines []int
+		for _, rule := range ruleset {
+			if ok, _ := rule.Match(file); ok {
+				lines = append(lines, rule.LineNumber)
+				matched[rule.LineNumber]++
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		last := lines[len(lines)-1]
+		effective[last]++
+		for _, line := range lines[:len(lines)-1] {
+			if overriders[line] == nil {
+				overriders[line] = map[int]bool{}
+			}
+			overriders[line][last] = true
+		}
+	}
+
+	var suggestions []report.Suggestion
+	for _, rule := range ruleset {
+		line := rule.LineNumber
+		switch {
+		case skip[line] || effective[line] > 0:
+		case matched[line] == 0:
+			suggestions = append(suggestions, report.Suggestion{
+				Kind:    report.SuggestionUnused,
+				Action:  "remove",
+				Pattern: rule.RawPattern(),
+				Lines:   []int{line},
+				Section: section,
+				Message: fmt.Sprintf("remove line %d: %s matches no tracked file", line, rule.RawPattern()),
+			})
+		default:
+			later := lo.Keys(overriders[line])
+			slices.Sort(later)
+			suggestions = append(suggestions, report.Suggestion{
+				Kind:    report.SuggestionOverridden,
+				Action:  "remove",
+				Pattern: rule.RawPattern(),
+				Lines:   []int{line},
+				Section: section,
+				Message: fmt.Sprintf("remove line %d: %s is overridden by %s for every file it matches (%s)", line, rule.RawPattern(), formatLines(later), pluralFiles(matched[line])),
+			})
+		}
+	}
+	return suggestions
+}
+
+// formatLines renders line numbers as "line 3" or "lines 3, 5".
+func formatLines(lines []int) string {
+	s := strings.Join(lo.Map(lines, func(line, _ int) string { return strconv.Itoa(line) }), ", ")
+	if len(lines) == 1 {
+		return "line " + s
+	}
+	return "lines " + s
+}
+
+// trackedFiles lists the files in the tree of HEAD, limited to --path-prefix.
+func trackedFiles(opts options) ([]string, error) {
+	repo, err := report.OpenRepository(".")
+	if err != nil {
+		return nil, fmt.Errorf("opening repository: %w", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+	iter, err := commit.Files()
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	err = iter.ForEach(func(f *object.File) error {
+		if opts.pathPrefix == "" || strings.HasPrefix(f.Name, opts.pathPrefix+"/") {
+			files = append(files, f.Name)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// writeLint writes the suggestions of the report as a list of CODEOWNERS
+// edits.
+func writeLint(w io.Writer, r report.Report) error {
+	if len(r.Suggestions) == 0 {
+		_, err := fmt.Fprintln(w, "No suggestions.")
+		return err
+	}
+	fmt.Fprintln(w, "Suggested CODEOWNERS edits:")
+	for _, s := range r.Suggestions {
+		section := ""
+		if s.Section != "" {
+			section = "[" + s.Section + "] "
+		}
+		if _, err := fmt.Fprintf(w, "  %s%s\n", section, s.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,151 +2,649 @@ package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"codeownerreport/report"
 
-	"github.com/go-git/go-git/v5"
 	"github.com/hmarr/codeowners"
 	"github.com/samber/lo"
 )
 
 func main() {
-	ruleset, err := loadRuleset()
+	opts := parseOptions()
+	if opts.check {
+		slog.SetLogLoggerLevel(slog.LevelWarn)
+	}
+
+	requirements, err := parseRequirements(opts.require)
 	if err != nil {
-		slog.Error("Error loading ruleset.", "error", err)
+		slog.Error("Invalid --require rule.", "error", err)
 		os.Exit(1)
 	}
 
-	repo, err := git.PlainOpen(".")
+	ownerRenames, err := parseOwnerRenames(opts.ownerRenames)
 	if err != nil {
-		slog.Error("Error opening repository.", "error", err)
+		slog.Error("Invalid --owner-rename.", "error", err)
 		os.Exit(1)
 	}
 
-	currentBranch, err := repo.Head()
+	webhookHeaders, err := parseWebhookHeaders(opts.webhookHeaders)
 	if err != nil {
-		slog.Error("Error getting current branch.", "error", err)
+		slog.Error("Invalid --webhook-header.", "error", err)
 		os.Exit(1)
 	}
-	if !currentBranch.Name().IsBranch() {
-		slog.Error("Not on a branch.")
+
+	knownOwners, err := loadKnownOwners(opts.knownOwners)
+	if err != nil {
+		slog.Error("Error loading known owners.", "error", err)
 		os.Exit(1)
 	}
-	slog.Info("Selected current branch.", "branch", currentBranch.Name().Short())
 
-	mainBranch, err := repo.Branch("main")
-	if errors.Is(err, git.ErrBranchNotFound) {
-		mainBranch, err = repo.Branch("master")
+	var teamMembers teams
+	if opts.teams != "" {
+		teamMembers, err = loadTeams(opts.teams)
+		if err != nil {
+			slog.Error("Error loading team mapping.", "error", err)
+			os.Exit(1)
+		}
 	}
-	if err != nil {
-		slog.Error("Error finding main branch.", "error", err)
-		os.Exit(1)
+
+	var manifest []codeowners.Rule
+	if opts.coverageManifest != "" {
+		manifest, err = loadPatterns(opts.coverageManifest)
+		if err != nil {
+			slog.Error("Error loading coverage manifest.", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	var weights map[string]float64
+	if opts.weights != "" {
+		weights, err = loadWeights(opts.weights)
+		if err != nil {
+			slog.Error("Error loading owner weights.", "error", err)
+			os.Exit(1)
+		}
 	}
 
-	mainRef, err := repo.Reference(mainBranch.Merge, true)
+	var unownedIgnore []codeowners.Rule
+	if opts.unownedIgnore != "" {
+		unownedIgnore, err = loadPatterns(opts.unownedIgnore)
+		if err != nil {
+			slog.Error("Error loading unowned ignore file.", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	pathspecs, err := mapErr(opts.pathspecs, func(pathspec string) (codeowners.Rule, error) {
+		return parsePattern(strings.TrimPrefix(pathspec, "./"))
+	})
 	if err != nil {
-		slog.Error("Error resolving main branch to reference.", "error", err)
+		slog.Error("Invalid --pathspec.", "error", err)
 		os.Exit(1)
 	}
-	mainCommit, err := repo.CommitObject(mainRef.Hash())
-	if err != nil {
-		slog.Error("Error resolving main branch to commit.", "error", err)
+
+	if !slices.Contains([]report.DiffMode{report.ThreeDot, report.TwoDot, report.Merge}, report.DiffMode(opts.diffMode)) {
+		slog.Error("Invalid --diff-mode.", "mode", opts.diffMode)
 		os.Exit(1)
 	}
 
-	slog.Info("Selected reference branch.", "branch", mainBranch.Name)
+	if opts.codeownersRev != "" && opts.githubPR != "" {
+		slog.Error("--codeowners-rev can't be combined with --github-pr.")
+		os.Exit(1)
+	}
+	if opts.codeownersRev != "" && opts.codeownersDiff {
+		slog.Error("--codeowners-rev can't be combined with --codeowners-diff, which compares the CODEOWNERS files of the base and the head.")
+		os.Exit(1)
+	}
 
-	currentCommit, err := repo.CommitObject(currentBranch.Hash())
-	if err != nil {
-		slog.Error("Error resolving HEAD commit.", "error", err)
+	if opts.compress != "" && opts.compress != "gzip" {
+		slog.Error("Invalid --compress.", "algorithm", opts.compress)
 		os.Exit(1)
 	}
 
-	baseCommits, err := currentCommit.MergeBase(mainCommit)
+	targets, err := parseTargets(opts.format, opts.output)
 	if err != nil {
-		slog.Error("Error resolving merge base commit.", "error", err)
+		slog.Error("Invalid output selection.", "error", err)
 		os.Exit(1)
 	}
 
-	if len(baseCommits) < 1 {
-		slog.Error("Could not find merge base.")
-		os.Exit(1)
+	if opts.pathPrefix != "" && opts.githubPR == "" {
+		if info, err := os.Stat(opts.pathPrefix); err != nil || !info.IsDir() {
+			slog.Error("Path prefix is not a directory.", "prefix", opts.pathPrefix)
+			os.Exit(1)
+		}
 	}
 
-	baseCommit := baseCommits[0]
+	if opts.checkEnv {
+		if !checkEnv(os.Stdout, opts) {
+			os.Exit(1)
+		}
+		return
+	}
 
-	slog.Info("Identified base commit.", "commit", baseCommit)
+	if opts.compareCodeowners {
+		if flag.NArg() != 2 {
+			slog.Error("--compare-codeowners needs two revisions.", "args", flag.Args())
+			os.Exit(1)
+		}
+		changes, err := compareCodeowners(flag.Arg(0), flag.Arg(1), opts)
+		if err != nil {
+			exit("Error comparing CODEOWNERS.", err)
+		}
+		writeRuleChanges(os.Stdout, flag.Arg(0), flag.Arg(1), changes)
+		return
+	}
 
-	baseTree, err := baseCommit.Tree()
-	if err != nil {
-		slog.Error("Error getting base commit tree.", "error", err)
-		os.Exit(1)
+	if opts.history > 0 {
+		if err := runHistory(opts); err != nil {
+			exit("Error determining ownership history.", err)
+		}
+		return
 	}
 
-	currentTree, err := currentCommit.Tree()
-	if err != nil {
-		slog.Error("Error getting current commit tree.", "error", err)
-		os.Exit(1)
+	var files map[string]report.ChangeType
+	var content []byte
+	branch := "HEAD"
+	meta := report.Meta{}
+	switch {
+	case opts.fileList != "":
+		files, err = readFileList(opts.fileList)
+		if err != nil {
+			slog.Error("Error reading file list.", "error", err)
+			os.Exit(1)
+		}
+		meta = report.NewMeta(report.Change{})
+		content, err = readCodeowners(opts)
+		if err != nil {
+			exit("Error reading CODEOWNERS.", err)
+		}
+	case opts.githubPR != "":
+		files, content, err = loadPullRequest(opts)
+		if err != nil {
+			exit("Error loading pull request.", err)
+		}
+		meta = report.NewMeta(report.Change{})
+	default:
+		change, err := report.LoadChange(".", report.ChangeOptions{
+			Base:             opts.base,
+			FirstParent:      opts.firstParent,
+			Commit:           opts.commit,
+			Retries:          opts.retries,
+			DiffMode:         report.DiffMode(opts.diffMode),
+			UseRemoteDefault: opts.useRemoteDefault,
+			ExcludeMerges:    opts.excludeMerges,
+		})
+		if err != nil {
+			exit("Error determining changed files.", err)
+		}
+
+		if change.BaseCommit != nil {
+			baseAge := time.Since(change.BaseCommit.Committer.When)
+			slog.Info("Identified base commit.", "commit", change.BaseCommit.Hash, "age", formatAge(baseAge))
+
+			if opts.warnStaleBase > 0 && baseAge > opts.warnStaleBase {
+				if opts.failStaleBase {
+					slog.Error("Base commit is stale, rebase the branch.", "age", formatAge(baseAge), "threshold", opts.warnStaleBase)
+					os.Exit(1)
+				}
+				slog.Warn("Base commit is stale, consider rebasing the branch.", "age", formatAge(baseAge), "threshold", opts.warnStaleBase)
+			}
+		}
+
+		if change.Behind {
+			if opts.failBehind {
+				slog.Error("Current branch is behind the base and has no commits of its own, check the branch or --base.", "branch", change.CurrentBranch, "base", change.BaseName)
+				os.Exit(1)
+			}
+			slog.Warn("Current branch is behind the base and has no commits of its own, check the branch or --base.", "branch", change.CurrentBranch, "base", change.BaseName)
+		}
+
+		if opts.explain {
+			explain(os.Stdout, change, len(change.Files))
+			return
+		}
+		if opts.codeownersDiff {
+			d, err := codeownersDiff(change, opts)
+			if err != nil {
+				exit("Error comparing CODEOWNERS.", err)
+			}
+			writeCodeownersDiff(os.Stdout, d, len(change.Files))
+			return
+		}
+
+		switch {
+		case change.CurrentBranch != "":
+			branch = change.CurrentBranch
+		case opts.commit != "":
+			branch = change.HeadCommit.Hash.String()
+		}
+		meta = report.NewMeta(change)
+		if opts.author == "" {
+			opts.author = change.HeadCommit.Author.Email
+		}
+		files = change.Files
+		content, err = readCodeowners(opts)
+		if err != nil {
+			exit("Error reading CODEOWNERS.", err)
+		}
 	}
 
-	diff, err := baseTree.Diff(currentTree)
+	ruleset, sections, err := loadRuleset(content, opts)
 	if err != nil {
-		slog.Error("Error determining diff between trees.", "error", err)
+		slog.Error("Error loading ruleset.", "error", err)
 		os.Exit(1)
 	}
+	if len(ruleset) == 0 {
+		slog.Warn("CODEOWNERS contains no rules, all files are unowned.")
+	}
+	if opts.lint {
+		for _, s := range sections {
+			for _, d := range duplicatePatterns(s.ruleset) {
+				slog.Warn("Pattern is listed more than once, only the last line takes effect.", "pattern", d.pattern, "lines", d.lines, "owners", d.owners)
+			}
+		}
+	}
 
-	patch, err := diff.Patch()
+	if opts.pathPrefix != "" {
+		all := len(files)
+		files = lo.PickBy(files, func(file string, _ report.ChangeType) bool {
+			return strings.HasPrefix(file, opts.pathPrefix+"/")
+		})
+		slog.Info("Limited changed files to path prefix.", "prefix", opts.pathPrefix, "files", len(files), "skipped", all-len(files))
+	}
+	if len(pathspecs) > 0 {
+		all := len(files)
+		files = lo.PickBy(files, func(file string, _ report.ChangeType) bool {
+			return matchesAny(pathspecs, file)
+		})
+		slog.Info("Limited changed files to pathspecs.", "files", len(files), "skipped", all-len(files))
+	}
+	if opts.respectGenerated {
+		generated, err := loadGenerated()
+		if err != nil {
+			slog.Error("Error reading generated files.", "error", err)
+			os.Exit(1)
+		}
+		all := len(files)
+		files = lo.OmitBy(files, func(file string, _ report.ChangeType) bool {
+			return generated(file)
+		})
+		slog.Info("Excluded generated files.", "files", len(files), "skipped", all-len(files))
+	}
+
+	if len(files) == 0 {
+		if opts.failOnEmpty {
+			slog.Error("No files changed.")
+			os.Exit(1)
+		}
+		slog.Info("No files changed.")
+	}
+
+	canonicalOwner := func(owner string) string { return owner }
+	if opts.foldOwnerCase {
+		canonicalOwner = ownerCaseFolder(ruleset)
+	}
+	if len(ownerRenames) > 0 {
+		fold := canonicalOwner
+		canonicalOwner = func(owner string) string {
+			if renamed, ok := ownerRenames[strings.ToLower(owner)]; ok {
+				return renamed
+			}
+			return fold(owner)
+		}
+	}
+
+	resolver := newSectionsResolver(sections, opts, canonicalOwner)
+	if canStream(targets, opts) {
+		resolver.discard()
+		if err := streamJSONL(targets[0], files, resolver, opts); err != nil {
+			slog.Error("Error writing report.", "format", targets[0].format, "output", targets[0].path, "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	r, err := report.Resolve(report.Change{Files: files}, resolver)
 	if err != nil {
-		slog.Error("Error getting patch from diff.", "error", err)
+		slog.Error("Failed to match rules.", "error", err)
 		os.Exit(1)
 	}
+	fileOwners := r.FileOwners()
+	fileRules := resolver.rules
+	sectionOwners := resolver.sectionOwners
 
-	fileOwners := map[string][]string{}
-
-	for _, fp := range patch.FilePatches() {
-		from, to := fp.Files()
-		if from != nil {
-			fileOwners[from.Path()] = nil
+	violations := checkRequirements(requirements, fileOwners)
+	unknownOwners := checkKnownOwners(knownOwners, fileRules)
+	if teamMembers != nil {
+		teamMembers.expandOwners(fileOwners)
+	}
+	if opts.me != "" {
+		me := "@" + strings.TrimPrefix(opts.me, "@")
+		all := len(fileOwners)
+		fileOwners = ownedBy(fileOwners, teamMembers.memberships(me))
+		slog.Info("Limited report to files owned by user.", "user", me, "files", len(fileOwners), "skipped", all-len(fileOwners))
+	}
+	if teamMembers != nil || opts.me != "" {
+		r = report.New(fileOwners)
+	}
+	r.Changes = lo.PickBy(files, func(_ string, change report.ChangeType) bool { return change != "" })
+	if opts.includeMeta {
+		r.Meta = &meta
+	}
+	if opts.gitlab {
+		sortSectionFiles(sectionOwners)
+		r.Sections = sectionOwners
+	}
+	if opts.showRule {
+		r.Matches = describeMatches(lo.Keys(fileOwners), fileRules)
+	}
+	if opts.includeAncestorOwners {
+		r.AncestorOwners = ancestorOwners(ruleset, fileOwners, fileRules, opts, canonicalOwner)
+	}
+	if manifest != nil {
+		r.ManifestCoverage = checkManifest(manifest, fileOwners)
+		if opts.strictCoverage {
+			r.Violations = append(r.Violations, manifestViolations(r.ManifestCoverage)...)
+		}
+	}
+	if weights != nil {
+		r.Reviewers = suggestReviewers(fileOwners, weights)
+	}
+	if opts.ruleCoverage {
+		r.RuleCoverage = ruleCoverage(fileRules)
+	}
+	if opts.flagCatchAll {
+		r.CatchAll = catchAllFiles(ruleset, fileRules, opts)
+	}
+	if len(unownedIgnore) > 0 {
+		if n := suppressUnowned(&r, unownedIgnore); n > 0 {
+			slog.Info("Suppressed ignored unowned files.", "count", n)
+		}
+	}
+	if opts.failOnUnowned {
+		r.Violations = append(r.Violations, unownedViolations(r)...)
+	}
+	for _, v := range violations {
+		r.Violations = append(r.Violations, v.report())
+	}
+	if opts.requireDirCoverage {
+		r.Violations = append(r.Violations, checkDirCoverage(fileOwners, sections, opts)...)
+	}
+	if opts.strictOwners {
+		for _, u := range unknownOwners {
+			r.Violations = append(r.Violations, u.report()...)
+		}
+	}
+	if slices.ContainsFunc(targets, func(t target) bool { return t.format == "codeowners" }) {
+		tracked, err := trackedFiles(opts)
+		if err == nil {
+			opts.trackedOwners, err = trackedOwners(tracked, newSectionsResolver(sections, opts, canonicalOwner), teamMembers)
+		}
+		if err != nil {
+			slog.Warn("Can't determine the owners of the tracked files, writing a rule per file.", "error", err)
+		}
+	}
+	if slices.ContainsFunc(targets, func(t target) bool { return t.format == "owners-file-lint" }) {
+		tracked, err := trackedFiles(opts)
+		if err != nil {
+			slog.Warn("Can't list tracked files, not looking for unused rules.", "error", err)
+			tracked = nil
 		}
-		if to != nil {
-			fileOwners[to.Path()] = nil
+		r.Suggestions = lintSuggestions(sections, tracked, r.Unowned, opts)
+	}
+	if opts.rollUpDepth > 0 {
+		r = rollUpReport(r, opts.rollUpDepth)
+	}
+	if opts.sort == "proximity" {
+		sortByProximity(&r)
+	}
+	if opts.unified {
+		r.Files = r.FileOwnerships()
+	}
+	if opts.urlTemplate != "" {
+		r.Links = fileLinks(opts.urlTemplate, branch, lo.Keys(r.FileOwners()))
+	}
+	if opts.diffReport != "" {
+		prev, err := readReport(opts.diffReport)
+		if err != nil {
+			slog.Error("Error reading previous report.", "error", err)
+			os.Exit(1)
 		}
+		delta := report.Compare(prev, r)
+		r.Delta = &delta
 	}
 
-	for file := range fileOwners {
-		rule, err := ruleset.Match(file)
+	switch {
+	case opts.check:
+		// Policy checks only, nothing to write.
+	case opts.violationsOnly:
+		writeViolations(os.Stdout, r.Violations)
+	case opts.countOnly:
+		if err := writeCounts(os.Stdout, r.Stats, opts.format == "json"); err != nil {
+			slog.Error("Error writing counts.", "error", err)
+			os.Exit(1)
+		}
+	case opts.reviewersOnly:
+		owners, err := sortOwners(r, opts.sort)
+		if err == nil {
+			err = writeReviewers(os.Stdout, withoutAuthor(owners, opts.author))
+		}
 		if err != nil {
-			slog.Error("Failed to match rule for file.", "file", file, "error", err)
-			continue
+			slog.Error("Error writing reviewers.", "error", err)
+			os.Exit(1)
+		}
+	default:
+		for _, target := range targets {
+			if err := target.write(r, opts); err != nil {
+				slog.Error("Error writing report.", "format", target.format, "output", target.path, "error", err)
+				os.Exit(1)
+			}
 		}
-		fileOwners[file] = lo.Map(rule.Owners, func(owner codeowners.Owner, index int) string {
-			return owner.String()
-		})
 	}
 
-	ownerFiles := map[string][]string{}
-	for file, owners := range fileOwners {
-		for _, owner := range owners {
-			ownerFiles[owner] = append(ownerFiles[owner], file)
+	// --check is a pass/fail gate and reports nowhere, not even to the
+	// webhook.
+	if opts.webhook != "" && !opts.check {
+		if err := postWebhook(opts.webhook, r, webhookHeaders, opts.webhookTimeout); err != nil {
+			if opts.webhookRequired {
+				slog.Error("Error posting report to webhook.", "error", err)
+				os.Exit(1)
+			}
+			slog.Warn("Error posting report to webhook.", "error", err)
 		}
 	}
-	for owner := range ownerFiles {
-		files := lo.Uniq(ownerFiles[owner])
-		fmt.Println()
-		fmt.Println(owner)
-		for _, file := range files {
-			fmt.Printf("  %s\n", file)
+
+	for _, v := range violations {
+		slog.Error("File is not owned by required owner.", "file", v.file, "pattern", v.requirement.pattern, "owner", v.requirement.owner)
+	}
+	for _, u := range unknownOwners {
+		slog.Warn("Owner is not a known owner.", "owner", u.owner, "line", u.rule.LineNumber, "pattern", u.rule.RawPattern(), "files", u.files)
+	}
+	if opts.failOnUnowned && len(r.Unowned) > 0 {
+		slog.Error("Changed files have no owner.", "files", r.Unowned)
+	}
+	for _, v := range r.Violations {
+		if v.Rule == report.RuleDirCoverage {
+			slog.Error("Directory is not fully owned.", "directory", v.File)
 		}
 	}
+	for _, entry := range r.ManifestCoverage {
+		if len(entry.Unowned) > 0 {
+			slog.Warn("Files that must be owned have no owner.", "pattern", entry.Pattern, "files", entry.Unowned)
+		}
+	}
+	if len(r.Violations) > 0 {
+		os.Exit(1)
+	}
 }
 
-func loadRuleset() (codeowners.Ruleset, error) {
-	f, err := os.Open(".github/CODEOWNERS")
+// readFileList reads the paths listed in the file at path, or stdin for "-".
+// Blank lines are ignored. Paths must be relative to the repository root.
+func readFileList(path string) (map[string]report.ChangeType, error) {
+	var content []byte
+	var err error
+	if path == "-" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(path)
+	}
 	if err != nil {
 		return nil, err
 	}
+	files := map[string]report.ChangeType{}
+	for i, line := range strings.Split(string(content), "\n") {
+		file := strings.TrimSpace(line)
+		if file == "" {
+			continue
+		}
+		clean := filepath.ToSlash(filepath.Clean(file))
+		if filepath.IsAbs(file) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return nil, fmt.Errorf("line %d: %s is not relative to the repository root", i+1, file)
+		}
+		files[clean] = ""
+	}
+	return files, nil
+}
+
+// readCodeowners reads the CODEOWNERS file given by --codeowners, from the
+// tree of --codeowners-rev if set.
+func readCodeowners(opts options) ([]byte, error) {
+	if opts.codeownersRev == "" {
+		return report.ReadCodeowners(opts.codeowners)
+	}
+	if strings.Contains(opts.codeowners, "://") || filepath.IsAbs(opts.codeowners) {
+		return nil, fmt.Errorf("CODEOWNERS location %s is not inside the repository, so it can't be read from revision %s", opts.codeowners, opts.codeownersRev)
+	}
+	repo, err := report.OpenRepository(".")
+	if err != nil {
+		return nil, fmt.Errorf("opening repository: %w", err)
+	}
+	commit, err := report.ResolveCommit(repo, opts.codeownersRev)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Reading CODEOWNERS from revision.", "revision", opts.codeownersRev, "commit", commit.Hash)
+	return report.ReadCodeownersAt(commit, filepath.ToSlash(filepath.Clean(opts.codeowners)))
+}
+
+func readReport(path string) (report.Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return report.Report{}, err
+	}
 	defer f.Close()
 
-	return codeowners.ParseFile(f)
+	return report.ReadJSON(f)
+}
+
+// exitCodes maps the errors of the report package to distinct exit codes and
+// hints. All other errors exit with 1, as do policy violations.
+var exitCodes = []struct {
+	err  error
+	code int
+	hint string
+}{
+	{report.ErrNoCodeowners, 3, "Add a CODEOWNERS file or point --codeowners to one."},
+	{report.ErrDetachedHead, 4, "Check out a branch first."},
+	{report.ErrNoBaseBranch, 5, "Select a base with --base."},
+	{report.ErrNoMergeBase, 6, "The current branch shares no history with the base."},
+}
+
+// exit logs err with msg and exits with the code mapped to err.
+func exit(msg string, err error) {
+	for _, e := range exitCodes {
+		if errors.Is(err, e.err) {
+			slog.Error(msg, "error", err, "hint", e.hint)
+			os.Exit(e.code)
+		}
+	}
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// rollUpReport replaces the files of r with their directories at the given
+// depth. Stats keep describing the individual files.
+func rollUpReport(r report.Report, depth int) report.Report {
+	rollUpAll := func(files []string) []string {
+		return lo.Uniq(lo.Map(files, func(file string, _ int) string {
+			return rollUp(file, depth)
+		}))
+	}
+	for owner, files := range r.Owners {
+		r.Owners[owner] = rollUpAll(files)
+	}
+	r.Unowned = rollUpAll(r.Unowned)
+	return r
+}
+
+// rollUp returns the directory of file at the given depth, with a trailing
+// slash. Files that are not nested at least depth directories deep, as well as
+// any file when depth is not positive, are returned unchanged.
+func rollUp(file string, depth int) string {
+	if depth <= 0 {
+		return file
+	}
+	segments := strings.Split(file, "/")
+	if len(segments) <= depth {
+		return file
+	}
+	return strings.Join(segments[:depth], "/") + "/"
+}
+
+// fileLinks renders the URL template for each file, substituting {branch} and
+// {path}. Both are escaped per path segment, keeping their slashes.
+func fileLinks(template, branch string, files []string) map[string]string {
+	escape := func(s string) string {
+		return strings.Join(lo.Map(strings.Split(s, "/"), func(segment string, _ int) string {
+			return url.PathEscape(segment)
+		}), "/")
+	}
+	links := map[string]string{}
+	for _, file := range files {
+		links[file] = strings.NewReplacer("{branch}", escape(branch), "{path}", escape(file)).Replace(template)
+	}
+	return links
+}
+
+// formatAge renders d in the largest whole unit that fits, e.g. "3 days".
+func formatAge(d time.Duration) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s", unit)
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	switch {
+	case d >= 24*time.Hour:
+		return plural(int(d/(24*time.Hour)), "day")
+	case d >= time.Hour:
+		return plural(int(d/time.Hour), "hour")
+	default:
+		return plural(int(d/time.Minute), "minute")
+	}
+}
+
+// explain prints how the change to report on was determined.
+func explain(w io.Writer, change report.Change, changedFiles int) {
+	base := "(none, root commit)"
+	if change.BaseCommit != nil {
+		base = change.BaseCommit.Hash.String()
+	}
+	current := change.CurrentBranch
+	if current == "" {
+		current = change.HeadCommit.Hash.String()
+	}
+	fmt.Fprintf(w, "Current branch: %s\n", current)
+	fmt.Fprintf(w, "Base branch:    %s\n", change.BaseName)
+	fmt.Fprintf(w, "Merge base:     %s\n", base)
+	fmt.Fprintf(w, "Changed files:  %d\n", changedFiles)
 }
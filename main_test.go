@@ -0,0 +1,15 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadCodeownersRevOutsideRepository(t *testing.T) {
+	for _, location := range []string{"https://example.com/CODEOWNERS", "/etc/CODEOWNERS"} {
+		_, err := readCodeowners(options{codeowners: location, codeownersRev: "HEAD"})
+		if err == nil || !strings.Contains(err.Error(), "is not inside the repository") {
+			t.Errorf("%s: err = %v, want an error about the location", location, err)
+		}
+	}
+}
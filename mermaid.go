@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"codeownerreport/report"
+
+	"github.com/samber/lo"
+)
+
+// mermaidEscaper replaces the characters that end or confuse a quoted Mermaid
+// label with entity codes. # comes first as it starts the entity codes itself.
+var mermaidEscaper = strings.NewReplacer("#", "#35;", `"`, "#quot;", "<", "#lt;", ">", "#gt;")
+
+// writeMermaid writes the report as a Mermaid flowchart with an edge from each
+// owner to every directory containing files it owns, labeled with the number
+// of files. Unowned files are attributed to an "(unowned)" node.
+func writeMermaid(w io.Writer, r report.Report, owners []string) error {
+	var dirs []string
+	for _, files := range r.Owners {
+		dirs = append(dirs, lo.Map(files, mermaidDir)...)
+	}
+	dirs = append(dirs, lo.Map(r.Unowned, mermaidDir)...)
+	dirs = lo.Uniq(dirs)
+	slices.Sort(dirs)
+	dirIDs := map[string]string{}
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, dir := range dirs {
+		dirIDs[dir] = fmt.Sprintf("d%d", i)
+		fmt.Fprintf(&b, "  d%d[\"%s\"]\n", i, mermaidEscaper.Replace(dir))
+	}
+	edges := func(id, label string, files []string) {
+		fmt.Fprintf(&b, "  %s([\"%s\"])\n", id, mermaidEscaper.Replace(label))
+		counts := lo.CountValues(lo.Map(files, mermaidDir))
+		for _, dir := range sortedKeys(counts) {
+			fmt.Fprintf(&b, "  %s -->|%d| %s\n", id, counts[dir], dirIDs[dir])
+		}
+	}
+	for i, owner := range owners {
+		edges(fmt.Sprintf("o%d", i), owner, r.Owners[owner])
+	}
+	if len(r.Unowned) > 0 {
+		edges("unowned", "(unowned)", r.Unowned)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidDir returns the directory a file is shown in, with / for the
+// repository root. Rolled up directories are shown as themselves.
+func mermaidDir(file string, _ int) string {
+	if strings.HasSuffix(file, "/") {
+		return file
+	}
+	if dir := path.Dir(file); dir != "." {
+		return dir + "/"
+	}
+	return "/"
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+type options struct {
+	rollUpDepth           int
+	caseInsensitive       bool
+	format                string
+	require               stringsFlag
+	codeowners            string
+	warnStaleBase         time.Duration
+	failStaleBase         bool
+	sort                  string
+	explain               bool
+	base                  string
+	normalizePaths        bool
+	output                stringsFlag
+	knownOwners           string
+	strictOwners          bool
+	githubPR              string
+	author                string
+	showRule              bool
+	firstParent           bool
+	unownedIgnore         string
+	failOnUnowned         bool
+	pathPrefix            string
+	maxFilesPerOwner      int
+	stats                 bool
+	commit                string
+	retries               int
+	diffReport            string
+	indent                int
+	bullet                string
+	flagCatchAll          bool
+	check                 bool
+	foldOwnerCase         bool
+	diffMode              string
+	webhook               string
+	webhookHeaders        stringsFlag
+	webhookTimeout        time.Duration
+	webhookRequired       bool
+	includeAncestorOwners bool
+	violationsOnly        bool
+	teams                 string
+	pathspecs             stringsFlag
+	ruleCoverage          bool
+	history               int
+	weights               string
+	urlTemplate           string
+	useRemoteDefault      bool
+	gitlab                bool
+	lint                  bool
+	noMarkers             bool
+	ownerRenames          stringsFlag
+	failOnEmpty           bool
+	expandBraces          bool
+	codeownersDiff        bool
+	me                    string
+	coverageManifest      string
+	strictCoverage        bool
+	includeMeta           bool
+	reviewersOnly         bool
+	compareCodeowners     bool
+	countOnly             bool
+	fileList              string
+	excludeMerges         bool
+	requireDirCoverage    bool
+	compress              string
+	codeownersRev         string
+	collapseThreshold     int
+	verbose               bool
+	checkEnv              bool
+	respectGenerated      bool
+	unified               bool
+	failBehind            bool
+
+	// trackedOwners maps every tracked file to its owners. It is not a flag,
+	// but filled in by main for the codeowners format.
+	trackedOwners map[string][]string
+}
+
+func parseOptions() options {
+	var opts options
+	flag.IntVar(&opts.rollUpDepth, "roll-up-depth", 0, "Roll up changed files to their directory at depth `N` instead of listing them individually.")
+	flag.BoolVar(&opts.caseInsensitive, "case-insensitive", false, "Match paths against CODEOWNERS patterns case-insensitively (GitHub matches case-sensitively).")
+	flag.StringVar(&opts.format, "format", "text", "Comma-separated output `formats`: text, json, jsonl, markdown, github-review, sarif, xml, csv-wide, tsv, properties, codeowners, mermaid or owners-file-lint.")
+	flag.Var(&opts.require, "require", "Require files matching a pattern to be owned by an owner, given as `pattern=owner`. Can be repeated.")
+	flag.StringVar(&opts.codeowners, "codeowners", ".github/CODEOWNERS", "`Path` or HTTP(S) URL of the CODEOWNERS file.")
+	flag.DurationVar(&opts.warnStaleBase, "warn-stale-base", 0, "Warn when the merge base commit is older than `duration`.")
+	flag.BoolVar(&opts.failStaleBase, "fail-stale-base", false, "Fail instead of warning when the merge base is older than --warn-stale-base.")
+	flag.StringVar(&opts.sort, "sort", "name", "Owner sort `order`: name, count (most files first), type (teams, then users, then emails) or proximity (by name, listing the files of each directory together).")
+	flag.BoolVar(&opts.explain, "explain", false, "Print the selected branches, merge base and number of changed files, then exit without a report.")
+	flag.StringVar(&opts.base, "base", "", "Base `revision` (branch, tag or commit) to compare against. Defaults to the CI target branch, then main or master.")
+	flag.BoolVar(&opts.normalizePaths, "normalize-paths", runtime.GOOS == "windows", "Convert backslashes in paths to forward slashes before matching. Enabled by default on Windows.")
+	flag.Var(&opts.output, "output", "Write a format to a file instead of stdout, given as `path` (matched to --format by position) or format=path. Can be repeated.")
+	flag.StringVar(&opts.knownOwners, "known-owners", "", "`File` listing the valid owners, one per line. Matched owners missing from it are reported.")
+	flag.BoolVar(&opts.strictOwners, "strict-owners", false, "Fail when an owner is not listed in --known-owners.")
+	flag.StringVar(&opts.githubPR, "github-pr", "", "Report on a GitHub pull request, given as `owner/repo#number`, instead of the local branch. Reads a token from GITHUB_TOKEN.")
+	flag.StringVar(&opts.author, "author", "", "`Handle` or email address of the change's author, excluded from reviewers. Defaults to the email address of the head commit's author.")
+	flag.BoolVar(&opts.showRule, "show-rule", false, "Annotate each file with the CODEOWNERS rule it matched, and unowned files with the reason.")
+	flag.BoolVar(&opts.firstParent, "first-parent", false, "Only consider the first-parent history of the base when looking for the merge base.")
+	flag.StringVar(&opts.unownedIgnore, "unowned-ignore", "", "`File` of patterns, one per line, for files that are intentionally unowned.")
+	flag.BoolVar(&opts.failOnUnowned, "fail-on-unowned", false, "Fail when a changed file has no owner.")
+	flag.StringVar(&opts.pathPrefix, "path-prefix", "", "Only report on files below `dir`, matching them as if dir was the repository root. The CODEOWNERS file defaults to dir/.github/CODEOWNERS.")
+	flag.IntVar(&opts.maxFilesPerOwner, "max-files-per-owner", 0, "List at most `N` files per owner in text and markdown output (0 lists all).")
+	flag.BoolVar(&opts.stats, "stats", false, "Append the total numbers of files and owners to text output.")
+	flag.StringVar(&opts.commit, "commit", "", "Report on the changes of a single `commit` compared to its first parent, instead of the current branch.")
+	flag.IntVar(&opts.retries, "retries", 0, "Retry reading git objects up to `N` times after transient errors.")
+	flag.StringVar(&opts.diffReport, "diff-report", "", "Compare against a previous JSON report `file` and include what changed since.")
+	flag.IntVar(&opts.indent, "indent", 2, "Indent file lines in text output by `N` spaces.")
+	flag.StringVar(&opts.bullet, "bullet", "", "Start file lines in text output with `str`, e.g. \"- \".")
+	flag.BoolVar(&opts.flagCatchAll, "flag-catchall", false, "Mark files that are only owned through a catch-all \"*\" rule.")
+	flag.BoolVar(&opts.check, "check", false, "Only run the policy checks: write no report, send no --webhook, log nothing but warnings and violations, and exit nonzero on any violation.")
+	flag.BoolVar(&opts.foldOwnerCase, "fold-owner-case", false, "Treat owners that only differ in case as the same owner, shown as first spelled in CODEOWNERS.")
+	flag.StringVar(&opts.diffMode, "diff-mode", "three-dot", "`Mode` of comparison: three-dot (changes since the merge base), two-dot (against the tip of the base) or merge (what merging into the base would change).")
+	flag.StringVar(&opts.webhook, "webhook", "", "POST the JSON report to `URL` after generating it. Skipped with --check.")
+	flag.Var(&opts.webhookHeaders, "webhook-header", "Add a `header` (Name: value) to the webhook request, e.g. for authentication. Can be repeated.")
+	flag.DurationVar(&opts.webhookTimeout, "webhook-timeout", 10*time.Second, "Timeout of a single webhook request.")
+	flag.BoolVar(&opts.webhookRequired, "webhook-required", false, "Fail when the report can't be delivered to the webhook.")
+	flag.BoolVar(&opts.includeAncestorOwners, "include-ancestor-owners", false, "Also list the owners of rules that match a file but are overridden by a later rule, separately from the required owners.")
+	flag.BoolVar(&opts.violationsOnly, "violations-only", false, "Print only policy violations, one per line, instead of the report. No output means all checks passed.")
+	flag.StringVar(&opts.teams, "teams", "", "`File` mapping teams to their members, one team per line followed by its members. Teams are replaced by their members in the report.")
+	flag.Var(&opts.pathspecs, "pathspec", "Only report on changed files matching the gitignore-style `pattern`, relative to the repository root. Can be repeated.")
+	flag.BoolVar(&opts.ruleCoverage, "rule-coverage", false, "List the rules that matched changed files with the number of files each matched.")
+	flag.IntVar(&opts.history, "history", 0, "Instead of a report, write the number of files each owner was responsible for in each of the last `N` commits as JSON.")
+	flag.StringVar(&opts.weights, "weights", "", "`File` of owner weights, one owner and weight per line, used to suggest a single reviewer per file. Owners not listed weigh 1.")
+	flag.StringVar(&opts.urlTemplate, "url-template", "", "Link files in Markdown output to this `URL`, replacing {branch} and {path}, e.g. https://github.com/org/repo/blob/{branch}/{path}.")
+	flag.BoolVar(&opts.useRemoteDefault, "use-remote-default", false, "Compare against the default branch of origin (origin/HEAD) instead of main or master, unless --base is given.")
+	flag.BoolVar(&opts.gitlab, "gitlab", false, "Parse GitLab CODEOWNERS sections and group the report by section.")
+	flag.BoolVar(&opts.lint, "lint", false, "Warn about likely mistakes in CODEOWNERS, such as patterns listed more than once.")
+	flag.BoolVar(&opts.noMarkers, "no-markers", false, "Don't prefix files in text output with their change type (A, M, D or R).")
+	flag.Var(&opts.ownerRenames, "owner-rename", "Report the owner `old=new` under its new name, e.g. after a team was renamed. Can be repeated.")
+	flag.BoolVar(&opts.failOnEmpty, "fail-on-empty", false, "Exit with an error if no files changed, e.g. because the base is misconfigured.")
+	flag.BoolVar(&opts.expandBraces, "expand-braces", false, "Expand brace alternatives in CODEOWNERS patterns, e.g. *.{js,ts}, into one rule each. This is not supported by GitHub.")
+	flag.BoolVar(&opts.codeownersDiff, "codeowners-diff", false, "Instead of a report, list how the CODEOWNERS file of the current branch changes the owners of the changed files compared to the base.")
+	flag.StringVar(&opts.me, "me", os.Getenv("CODEOWNERREPORT_ME"), "Only report on files owned by this `user` or a team it is a member of according to --teams, listing no other owners. Defaults to $CODEOWNERREPORT_ME.")
+	flag.StringVar(&opts.coverageManifest, "coverage-manifest", "", "`File` of patterns, one per line, for areas in which changed files must be owned. Reports the coverage of each.")
+	flag.BoolVar(&opts.strictCoverage, "strict-coverage", false, "Fail when a changed file matching --coverage-manifest is unowned.")
+	flag.BoolVar(&opts.includeMeta, "include-meta", false, "Start the report with the branches and commits it covers and when it was generated.")
+	flag.BoolVar(&opts.reviewersOnly, "reviewers-only", false, "Print only the owners to request reviews from, one per line, leaving out the author.")
+	flag.BoolVar(&opts.compareCodeowners, "compare-codeowners", false, "Instead of a report, list the CODEOWNERS rules that differ between the two revisions given as arguments.")
+	flag.BoolVar(&opts.countOnly, "count-only", false, "Print only the numbers of files, owned and unowned files and owners, as JSON with --format json.")
+	flag.StringVar(&opts.fileList, "file-list", "", "Report on the files listed in `file` (- for stdin), one path relative to the repository root per line, instead of the files changed on the branch.")
+	flag.BoolVar(&opts.excludeMerges, "exclude-merges", false, "Only count files changed by non-merge commits of the branch, and skip merge commits in --history.")
+	flag.BoolVar(&opts.requireDirCoverage, "require-dir-coverage", false, "Fail when a directory with changed files has no rule owning all of its files, e.g. only rules for certain extensions.")
+	flag.StringVar(&opts.compress, "compress", "", "Compress reports written to files with `algorithm` gzip, appending .gz to their names.")
+	flag.StringVar(&opts.codeownersRev, "codeowners-rev", "", "Read the CODEOWNERS file from the tree of `revision` instead of the working tree, independently of the compared changes.")
+	flag.IntVar(&opts.collapseThreshold, "collapse-threshold", 0, "In text and markdown output, show a directory with at least `N` files of an owner as the directory and its number of files (0 lists all).")
+	flag.BoolVar(&opts.verbose, "verbose", false, "List every file in text and markdown output, even with --collapse-threshold.")
+	flag.BoolVar(&opts.checkEnv, "check-env", false, "Instead of a report, check that the repository, HEAD, the base and the CODEOWNERS file can be found, and exit nonzero if any can't.")
+	flag.BoolVar(&opts.respectGenerated, "respect-generated", false, "Leave out files marked linguist-generated in .gitattributes, as GitHub hides them from reviews.")
+	flag.BoolVar(&opts.unified, "unified", false, "List all files sorted by path with their owners or (unowned), instead of grouped by owner. Adds the list to JSON output as files.")
+	flag.BoolVar(&opts.failBehind, "fail-behind", false, "Fail instead of warning when the current branch is behind the base, e.g. because the wrong branch is checked out.")
+	flag.Parse()
+
+	if opts.verbose {
+		opts.collapseThreshold = 0
+	}
+	if opts.pathPrefix != "" {
+		// The repository root itself, e.g. ".", means no prefix.
+		opts.pathPrefix = strings.Trim(path.Clean(filepath.ToSlash(opts.pathPrefix)), "/")
+		if opts.pathPrefix == "." {
+			opts.pathPrefix = ""
+		}
+	}
+	if opts.pathPrefix != "" && !isFlagSet("codeowners") {
+		opts.codeowners = path.Join(opts.pathPrefix, ".github/CODEOWNERS")
+	}
+	return opts
+}
+
+// stringsFlag is a flag that can be given multiple times, collecting all
+// values.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// isFlagSet reports whether the flag with the given name was passed explicitly.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
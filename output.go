@@ -0,0 +1,682 @@
+package main
+
+import (
+	"cmp"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"codeownerreport/report"
+
+	"github.com/samber/lo"
+)
+
+var formats = []string{"text", "json", "markdown", "github-review", "sarif", "xml", "csv-wide", "properties", "codeowners", "tsv", "jsonl", "mermaid", "owners-file-lint"}
+
+// formatName matches what an output of the form format=path could mean as
+// format. Paths that merely contain = don't.
+var formatName = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// target is a format to render the report in and where to write it to. An
+// empty path means stdout.
+type target struct {
+	format string
+	path   string
+}
+
+// parseTargets pairs the comma-separated formats with the given outputs.
+// Outputs of the form format=path are assigned to that format, which must be
+// one of the selected formats, all others are assigned to the remaining
+// formats in order. Formats without an output are written to stdout.
+func parseTargets(formatList string, outputs []string) ([]target, error) {
+	var targets []target
+	for _, format := range strings.Split(formatList, ",") {
+		format = strings.TrimSpace(format)
+		if !slices.Contains(formats, format) {
+			return nil, fmt.Errorf("unknown format %q", format)
+		}
+		if slices.ContainsFunc(targets, func(t target) bool { return t.format == format }) {
+			return nil, fmt.Errorf("format %q given more than once", format)
+		}
+		targets = append(targets, target{format: format})
+	}
+
+	var positional []string
+	for _, output := range outputs {
+		format, path, ok := strings.Cut(output, "=")
+		if !ok || !formatName.MatchString(format) {
+			positional = append(positional, output)
+			continue
+		}
+		if !slices.Contains(formats, format) {
+			return nil, fmt.Errorf("unknown format %q in output %q", format, output)
+		}
+		i := slices.IndexFunc(targets, func(t target) bool { return t.format == format })
+		if i < 0 {
+			return nil, fmt.Errorf("output %q is for format %q, which isn't selected", output, format)
+		}
+		if targets[i].path != "" {
+			return nil, fmt.Errorf("more than one output for format %q", format)
+		}
+		targets[i].path = path
+	}
+	for i := range targets {
+		if len(positional) == 0 {
+			break
+		}
+		if targets[i].path == "" {
+			targets[i].path = positional[0]
+			positional = positional[1:]
+		}
+	}
+	if len(positional) > 0 {
+		return nil, fmt.Errorf("more outputs than formats: %s", strings.Join(positional, ", "))
+	}
+	return targets, nil
+}
+
+func (t target) write(r report.Report, opts options) error {
+	return t.writeWith(opts, func(w io.Writer) error {
+		return writeReport(w, r, t.format, opts)
+	})
+}
+
+// writeWith calls write with the output of the target, compressing it if
+// requested.
+func (t target) writeWith(opts options, write func(io.Writer) error) error {
+	if t.path == "" || t.path == "-" {
+		return write(os.Stdout)
+	}
+
+	path := t.path
+	if opts.compress == "gzip" {
+		path += ".gz"
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = f
+	var zw *gzip.Writer
+	if opts.compress == "gzip" {
+		zw = gzip.NewWriter(f)
+		w = zw
+	}
+	err = write(w)
+	if err == nil && zw != nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeReport(w io.Writer, r report.Report, format string, opts options) error {
+	owners, err := sortOwners(r, opts.sort)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "text":
+		return writeText(w, r, owners, opts)
+	case "json":
+		return r.WriteJSON(w)
+	case "markdown":
+		return writeMarkdown(w, r, owners, opts)
+	case "github-review":
+		return writeGitHubReview(w, withoutAuthor(owners, opts.author))
+	case "sarif":
+		return writeSARIF(w, r)
+	case "xml":
+		return writeXML(w, r, owners)
+	case "csv-wide":
+		return writeCSVWide(w, r)
+	case "properties":
+		return writeProperties(w, r, owners)
+	case "codeowners":
+		return writeCodeowners(w, r, opts.trackedOwners, opts.pathPrefix)
+	case "tsv":
+		return writeTSV(w, r, owners)
+	case "jsonl":
+		return writeJSONL(w, r)
+	case "mermaid":
+		return writeMermaid(w, r, owners)
+	case "owners-file-lint":
+		return writeLint(w, r)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+func writeText(w io.Writer, r report.Report, owners []string, opts options) error {
+	prefix := strings.Repeat(" ", max(opts.indent, 0)) + opts.bullet
+	if r.Meta != nil {
+		writeMeta(w, *r.Meta, "# ")
+	}
+	writeFiles := func(files []string) error {
+		files, collapsed := collapse(files, opts.collapseThreshold)
+		files, more := truncate(files, opts.maxFilesPerOwner)
+		for _, file := range files {
+			if n, ok := collapsed[file]; ok {
+				if _, err := fmt.Fprintf(w, "%s%s%s (%s)\n", prefix, marker(r, file, opts), file, pluralFiles(n)); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "%s%s%s%s\n", prefix, marker(r, file, opts), file, annotation(r, file)); err != nil {
+				return err
+			}
+		}
+		if more > 0 {
+			_, err := fmt.Fprintf(w, "%s... and %d more\n", prefix, more)
+			return err
+		}
+		return nil
+	}
+
+	if len(r.Files) > 0 {
+		fmt.Fprintln(w)
+		for _, f := range r.Files {
+			fmt.Fprintf(w, "%s%s%s: %s%s\n", prefix, marker(r, f.File, opts), f.File, formatOwners(f.Owners), annotation(r, f.File))
+		}
+	} else if len(r.Sections) > 0 {
+		for _, name := range sortedKeys(r.Sections) {
+			fmt.Fprintf(w, "\n[%s]\n", name)
+			for _, owner := range owners {
+				if files, ok := r.Sections[name][owner]; ok {
+					fmt.Fprintln(w)
+					fmt.Fprintln(w, owner)
+					if err := writeFiles(files); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	} else {
+		for _, owner := range owners {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, owner)
+			if err := writeFiles(r.Owners[owner]); err != nil {
+				return err
+			}
+		}
+	}
+	if len(r.AncestorOwners) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Also notify (owners of overridden rules):")
+		for _, owner := range sortedKeys(r.AncestorOwners) {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, owner)
+			if err := writeFiles(r.AncestorOwners[owner]); err != nil {
+				return err
+			}
+		}
+	}
+	if len(r.Unowned) > 0 && len(r.Files) == 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "(unowned)")
+		if err := writeFiles(r.Unowned); err != nil {
+			return err
+		}
+	}
+	if len(r.ManifestCoverage) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Coverage manifest:")
+		for _, entry := range r.ManifestCoverage {
+			fmt.Fprintf(w, "%s%s: %s\n", prefix, entry.Pattern, manifestStatus(entry))
+		}
+	}
+	if len(r.Reviewers) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Suggested reviewers:")
+		for _, file := range sortedKeys(r.Reviewers) {
+			fmt.Fprintf(w, "%s%s: %s\n", prefix, file, r.Reviewers[file])
+		}
+	}
+	if len(r.RuleCoverage) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Rule coverage:")
+		for _, c := range r.RuleCoverage {
+			fmt.Fprintf(w, "%sline %d: %s (%s)\n", prefix, c.Line, c.Pattern, pluralFiles(c.Files))
+		}
+	}
+	if r.Delta != nil {
+		writeDelta(w, *r.Delta, prefix)
+	}
+	if opts.stats {
+		fmt.Fprintf(w, "\n%s\n", formatStats(r.Stats))
+		if len(r.Stats.MultiOwnerFiles) > 0 {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "Files with multiple owners:")
+			for _, f := range r.Stats.MultiOwnerFiles {
+				fmt.Fprintf(w, "%s%s (%d owners)\n", prefix, f.File, f.Owners)
+			}
+		}
+	}
+	return nil
+}
+
+// writeMeta writes the known fields of meta, one per line starting with
+// prefix.
+func writeMeta(w io.Writer, meta report.Meta, prefix string) {
+	for _, field := range [][2]string{
+		{"Branch", meta.Branch},
+		{"Base", meta.Base},
+		{"Base commit", meta.BaseCommit},
+		{"Head commit", meta.HeadCommit},
+		{"Generated", meta.GeneratedAt.Format(time.RFC3339)},
+	} {
+		if field[1] != "" {
+			fmt.Fprintf(w, "%s%s: %s\n", prefix, field[0], field[1])
+		}
+	}
+}
+
+// writeDelta lists the changes compared to a previous report, starting every
+// item with prefix.
+func writeDelta(w io.Writer, d report.Delta, prefix string) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Changes since previous report:")
+	if d.Empty() {
+		fmt.Fprintf(w, "%snone\n", prefix)
+	}
+	for _, owner := range d.AddedOwners {
+		fmt.Fprintf(w, "%sadded owner %s\n", prefix, owner)
+	}
+	for _, owner := range d.RemovedOwners {
+		fmt.Fprintf(w, "%sremoved owner %s\n", prefix, owner)
+	}
+	for _, file := range d.NewlyUnowned {
+		fmt.Fprintf(w, "%snewly unowned %s\n", prefix, file)
+	}
+	for _, c := range d.Changed {
+		fmt.Fprintf(w, "%s%s: %s -> %s\n", prefix, c.File, formatOwners(c.Before), formatOwners(c.After))
+	}
+}
+
+func formatOwners(owners []string) string {
+	if len(owners) == 0 {
+		return "(unowned)"
+	}
+	return strings.Join(owners, " ")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := lo.Keys(m)
+	slices.Sort(keys)
+	return keys
+}
+
+// writeViolations lists policy violations one per line, writing nothing if
+// there are none.
+func writeViolations(w io.Writer, violations []report.Violation) {
+	for _, v := range violations {
+		fmt.Fprintf(w, "%s: %s\n", v.Rule, v.Message)
+	}
+}
+
+// writeJSONL writes one JSON object per file and line, in path order, in the
+// same shape report.Stream emits.
+func writeJSONL(w io.Writer, r report.Report) error {
+	enc := json.NewEncoder(w)
+	for _, f := range r.FileOwnerships() {
+		if err := enc.Encode(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// canStream reports whether the report can be streamed file by file with
+// report.Stream instead of being built in memory: when jsonl is the only
+// output and nothing needs the complete report, such as policy checks, team
+// expansion, roll-ups or the webhook.
+func canStream(targets []target, opts options) bool {
+	return len(targets) == 1 && targets[0].format == "jsonl" &&
+		!opts.check && !opts.violationsOnly && !opts.countOnly && !opts.reviewersOnly &&
+		opts.teams == "" && opts.me == "" && opts.rollUpDepth == 0 &&
+		len(opts.require) == 0 && opts.knownOwners == "" && opts.unownedIgnore == "" && !opts.failOnUnowned &&
+		opts.coverageManifest == "" && !opts.requireDirCoverage && opts.weights == "" &&
+		opts.diffReport == "" && opts.webhook == ""
+}
+
+// streamJSONL writes the owners of the changed files to t as jsonl, resolving
+// and writing one file at a time. Owners are sorted like in the report.
+func streamJSONL(t target, files map[string]report.ChangeType, resolver report.OwnerResolver, opts options) error {
+	return t.writeWith(opts, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		return report.Stream(context.Background(), report.Change{Files: files}, resolver, func(f report.FileOwnership) error {
+			slices.Sort(f.Owners)
+			return enc.Encode(f)
+		})
+	})
+}
+
+// collapse replaces the files of every directory with at least threshold of
+// them by the directory, at the position of its first file, and returns the
+// number of files of each collapsed directory. Nothing is collapsed if
+// threshold is not positive. Rolled up directories are kept as they are.
+func collapse(files []string, threshold int) ([]string, map[string]int) {
+	if threshold <= 0 {
+		return files, nil
+	}
+	dir := func(file string) string {
+		if d := path.Dir(file); d != "." {
+			return d + "/"
+		}
+		return "/"
+	}
+	counts := lo.CountValues(lo.FilterMap(files, func(file string, _ int) (string, bool) {
+		return dir(file), !strings.HasSuffix(file, "/")
+	}))
+	collapsed := map[string]int{}
+	var result []string
+	for _, file := range files {
+		if strings.HasSuffix(file, "/") || counts[dir(file)] < threshold {
+			result = append(result, file)
+			continue
+		}
+		if _, ok := collapsed[dir(file)]; !ok {
+			collapsed[dir(file)] = counts[dir(file)]
+			result = append(result, dir(file))
+		}
+	}
+	return result, collapsed
+}
+
+// truncate returns the first max files, or all of them if max is not
+// positive, and the number of files left out.
+func truncate(files []string, max int) ([]string, int) {
+	if max <= 0 || len(files) <= max {
+		return files, 0
+	}
+	return files[:max], len(files) - max
+}
+
+// manifestStatus summarizes the coverage of a manifest entry.
+func manifestStatus(entry report.ManifestEntry) string {
+	switch {
+	case entry.Files == 0:
+		return "not touched"
+	case len(entry.Unowned) == 0:
+		return fmt.Sprintf("%s, all owned", pluralFiles(entry.Files))
+	default:
+		return fmt.Sprintf("%d of %s unowned: %s", len(entry.Unowned), pluralFiles(entry.Files), strings.Join(entry.Unowned, ", "))
+	}
+}
+
+func pluralFiles(n int) string {
+	if n == 1 {
+		return "1 file"
+	}
+	return fmt.Sprintf("%d files", n)
+}
+
+func formatStats(stats report.Stats) string {
+	return fmt.Sprintf("%d files, %d owned, %d unowned, %d owners.",
+		stats.Files, stats.OwnedFiles, stats.UnownedFiles, stats.Owners)
+}
+
+// marker returns the change type of file followed by a space, padded to the
+// same width if it is unknown, or nothing if markers are disabled.
+func marker(r report.Report, file string, opts options) string {
+	if opts.noMarkers || len(r.Changes) == 0 {
+		return ""
+	}
+	if change, ok := r.Changes[file]; ok {
+		return string(change) + " "
+	}
+	return "  "
+}
+
+// annotation describes the rule a file matched, if the report contains
+// matches, and whether it is only owned through a catch-all rule.
+func annotation(r report.Report, file string) string {
+	var notes []string
+	if m, ok := r.Matches[file]; ok {
+		if m.Reason != "" {
+			notes = append(notes, m.Reason)
+		} else {
+			notes = append(notes, fmt.Sprintf("line %d: %s", m.Line, m.Pattern))
+		}
+	}
+	if _, ok := slices.BinarySearch(r.CatchAll, file); ok {
+		notes = append(notes, "catch-all only")
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(notes, ", ") + ")"
+}
+
+func writeMarkdown(w io.Writer, r report.Report, owners []string, opts options) error {
+	writeFiles := func(files []string) {
+		files, collapsed := collapse(files, opts.collapseThreshold)
+		files, more := truncate(files, opts.maxFilesPerOwner)
+		for _, file := range files {
+			if n, ok := collapsed[file]; ok {
+				fmt.Fprintf(w, "- `%s` (%s)\n", file, pluralFiles(n))
+			} else if link, ok := r.Links[file]; ok {
+				fmt.Fprintf(w, "- [`%s`](%s)\n", file, link)
+			} else {
+				fmt.Fprintf(w, "- `%s`\n", file)
+			}
+		}
+		if more > 0 {
+			fmt.Fprintf(w, "- ... and %d more\n", more)
+		}
+	}
+
+	if r.Meta != nil {
+		fmt.Fprintln(w, "<!--")
+		writeMeta(w, *r.Meta, "")
+		fmt.Fprintln(w, "-->")
+	}
+	fmt.Fprintln(w, "# Code owners")
+	if len(r.Files) > 0 {
+		fmt.Fprintln(w)
+		for _, f := range r.Files {
+			name := "`" + f.File + "`"
+			if link, ok := r.Links[f.File]; ok {
+				name = fmt.Sprintf("[%s](%s)", name, link)
+			}
+			fmt.Fprintf(w, "- %s: %s\n", name, formatOwners(f.Owners))
+		}
+	} else if len(r.Sections) > 0 {
+		for _, name := range sortedKeys(r.Sections) {
+			fmt.Fprintf(w, "\n## [%s]\n", name)
+			for _, owner := range owners {
+				if files, ok := r.Sections[name][owner]; ok {
+					fmt.Fprintf(w, "\n### %s\n\n", owner)
+					writeFiles(files)
+				}
+			}
+		}
+	} else {
+		for _, owner := range owners {
+			fmt.Fprintf(w, "\n## %s\n\n", owner)
+			writeFiles(r.Owners[owner])
+		}
+	}
+	if len(r.AncestorOwners) > 0 {
+		fmt.Fprint(w, "\n## Also notify\n\nOwners of rules that match but are overridden by a later rule.\n")
+		for _, owner := range sortedKeys(r.AncestorOwners) {
+			fmt.Fprintf(w, "\n### %s\n\n", owner)
+			writeFiles(r.AncestorOwners[owner])
+		}
+	}
+	if len(r.Unowned) > 0 && len(r.Files) == 0 {
+		fmt.Fprint(w, "\n## Unowned\n\n")
+		writeFiles(r.Unowned)
+	}
+	if len(r.ManifestCoverage) > 0 {
+		fmt.Fprint(w, "\n## Coverage manifest\n\n")
+		for _, entry := range r.ManifestCoverage {
+			fmt.Fprintf(w, "- `%s`: %s\n", entry.Pattern, manifestStatus(entry))
+		}
+	}
+	if len(r.Reviewers) > 0 {
+		fmt.Fprint(w, "\n## Suggested reviewers\n\n")
+		for _, file := range sortedKeys(r.Reviewers) {
+			fmt.Fprintf(w, "- `%s`: %s\n", file, r.Reviewers[file])
+		}
+	}
+	if len(r.RuleCoverage) > 0 {
+		fmt.Fprint(w, "\n## Rule coverage\n\n| Line | Pattern | Files |\n| ---: | --- | ---: |\n")
+		for _, c := range r.RuleCoverage {
+			fmt.Fprintf(w, "| %d | `%s` | %d |\n", c.Line, c.Pattern, c.Files)
+		}
+	}
+	if r.Delta != nil {
+		writeDelta(w, *r.Delta, "- ")
+	}
+	fmt.Fprintf(w, "\n%s\n", formatStats(r.Stats))
+	if opts.stats && len(r.Stats.MultiOwnerFiles) > 0 {
+		fmt.Fprint(w, "\n## Files with multiple owners\n\n")
+		for _, f := range r.Stats.MultiOwnerFiles {
+			_, err := fmt.Fprintf(w, "- `%s` (%d owners)\n", f.File, f.Owners)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeGitHubReview writes a request body for the GitHub "request reviewers"
+// API. Email owners can't be requested and are skipped.
+func writeGitHubReview(w io.Writer, owners []string) error {
+	body := struct {
+		Reviewers     []string `json:"reviewers"`
+		TeamReviewers []string `json:"team_reviewers"`
+	}{
+		Reviewers:     []string{},
+		TeamReviewers: []string{},
+	}
+	for _, owner := range owners {
+		name, ok := strings.CutPrefix(owner, "@")
+		if !ok {
+			continue
+		}
+		if _, team, ok := strings.Cut(name, "/"); ok {
+			body.TeamReviewers = append(body.TeamReviewers, team)
+		} else {
+			body.Reviewers = append(body.Reviewers, name)
+		}
+	}
+	body.Reviewers = lo.Uniq(body.Reviewers)
+	body.TeamReviewers = lo.Uniq(body.TeamReviewers)
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(body)
+}
+
+// writeCounts writes the counts of stats, leaving out any file listings.
+func writeCounts(w io.Writer, stats report.Stats, asJSON bool) error {
+	stats.MultiOwnerFiles = nil
+	if !asJSON {
+		_, err := fmt.Fprintln(w, formatStats(stats))
+		return err
+	}
+	return json.NewEncoder(w).Encode(stats)
+}
+
+// writeReviewers lists the owners to request reviews from, one per line.
+func writeReviewers(w io.Writer, owners []string) error {
+	for _, owner := range owners {
+		if _, err := fmt.Fprintln(w, owner); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withoutAuthor removes the author, given as handle or email address, from
+// owners. Teams stay, as the author's membership is unknown unless teams were
+// expanded already.
+func withoutAuthor(owners []string, author string) []string {
+	if author == "" {
+		return owners
+	}
+	handle := "@" + strings.TrimPrefix(author, "@")
+	kept := lo.Reject(owners, func(owner string, _ int) bool {
+		return strings.EqualFold(owner, handle) || strings.EqualFold(owner, author)
+	})
+	if len(kept) < len(owners) {
+		slog.Info("Removed the author from the reviewers.", "author", author)
+	}
+	return kept
+}
+
+// sortOwners returns the owners of r in the given order.
+func sortOwners(r report.Report, order string) ([]string, error) {
+	owners := r.OwnerNames()
+	switch order {
+	case "name", "proximity":
+	case "count":
+		slices.SortStableFunc(owners, func(a, b string) int {
+			return cmp.Compare(len(r.Owners[b]), len(r.Owners[a]))
+		})
+	case "type":
+		slices.SortStableFunc(owners, func(a, b string) int {
+			return cmp.Compare(ownerTypeRank(a), ownerTypeRank(b))
+		})
+	default:
+		return nil, fmt.Errorf("unknown sort order %q", order)
+	}
+	return owners, nil
+}
+
+// sortByProximity sorts the files of every owner, section and the unowned
+// files by directory, then by name, so files of the same directory are listed
+// together ahead of those in subdirectories.
+func sortByProximity(r *report.Report) {
+	sortFiles := func(files []string) {
+		slices.SortFunc(files, compareProximity)
+	}
+	for _, files := range r.Owners {
+		sortFiles(files)
+	}
+	for _, owners := range r.Sections {
+		for _, files := range owners {
+			sortFiles(files)
+		}
+	}
+	for _, files := range r.AncestorOwners {
+		sortFiles(files)
+	}
+	sortFiles(r.Unowned)
+}
+
+// compareProximity orders files by directory, then by file name.
+func compareProximity(a, b string) int {
+	dirA, nameA := path.Split(a)
+	dirB, nameB := path.Split(b)
+	return cmp.Or(cmp.Compare(dirA, dirB), cmp.Compare(nameA, nameB))
+}
+
+// ownerTypeRank classifies an owner by its string form: teams (@org/team)
+// first, then users (@user), then email addresses.
+func ownerTypeRank(owner string) int {
+	switch {
+	case strings.HasPrefix(owner, "@") && strings.Contains(owner, "/"):
+		return 0
+	case strings.HasPrefix(owner, "@"):
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"codeownerreport/report"
+)
+
+func TestStreamJSONL(t *testing.T) {
+	opts := options{}
+	_, sections, err := loadRuleset([]byte("* @default\n*.go @gophers @alice\n"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]report.ChangeType{
+		"main.go":   report.Modified,
+		"README.md": report.Added,
+	}
+	r, err := report.Resolve(report.Change{Files: files}, newSectionsResolver(sections, opts, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want strings.Builder
+	if err := writeJSONL(&want, r); err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := newSectionsResolver(sections, opts, nil)
+	resolver.discard()
+	path := filepath.Join(t.TempDir(), "owners.jsonl")
+	if err := streamJSONL(target{format: "jsonl", path: path}, files, resolver, opts); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want.String() {
+		t.Errorf("streamed\n%s\nwant the report output\n%s", got, want.String())
+	}
+	if resolver.rules != nil || resolver.sectionOwners != nil {
+		t.Error("the resolver recorded matches while streaming")
+	}
+}
+
+func TestCanStream(t *testing.T) {
+	jsonl := []target{{format: "jsonl"}}
+	tests := []struct {
+		name    string
+		targets []target
+		opts    options
+		want    bool
+	}{
+		{"jsonl only", jsonl, options{}, true},
+		{"other format", []target{{format: "json"}}, options{}, false},
+		{"several outputs", []target{{format: "jsonl"}, {format: "json"}}, options{}, false},
+		{"teams", jsonl, options{teams: "TEAMS"}, false},
+		{"check", jsonl, options{check: true}, false},
+		{"webhook", jsonl, options{webhook: "https://example.com"}, false},
+	}
+	for _, tt := range tests {
+		if got := canStream(tt.targets, tt.opts); got != tt.want {
+			t.Errorf("%s: canStream = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseTargets(t *testing.T) {
+	targets, err := parseTargets("json,markdown", []string{"markdown=report.md", "out/a=b.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []target{{format: "json", path: "out/a=b.json"}, {format: "markdown", path: "report.md"}}
+	if !slices.Equal(targets, want) {
+		t.Errorf("parseTargets = %v, want %v", targets, want)
+	}
+
+	for _, output := range []string{"markdwon=x.md", "md=/tmp/x"} {
+		if _, err := parseTargets("json", []string{output}); err == nil || !strings.Contains(err.Error(), "unknown format") {
+			t.Errorf("--output %s: err = %v, want an unknown format error", output, err)
+		}
+	}
+	if _, err := parseTargets("json", []string{"markdown=x.md"}); err == nil || !strings.Contains(err.Error(), "isn't selected") {
+		t.Errorf("--output for an unselected format: err = %v, want an error", err)
+	}
+}
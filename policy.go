@@ -0,0 +1,286 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"codeownerreport/report"
+
+	"github.com/hmarr/codeowners"
+	"github.com/samber/lo"
+)
+
+// requirement demands that all files matching pattern are owned by owner.
+type requirement struct {
+	pattern string
+	owner   string
+	rule    codeowners.Rule
+}
+
+type violation struct {
+	file        string
+	requirement requirement
+}
+
+func (v violation) report() report.Violation {
+	return report.Violation{
+		Rule:    report.RuleRequiredOwner,
+		File:    v.file,
+		Message: fmt.Sprintf("%s matches %s and must be owned by %s", v.file, v.requirement.pattern, v.requirement.owner),
+	}
+}
+
+func parseRequirements(values []string) ([]requirement, error) {
+	return mapErr(values, parseRequirement)
+}
+
+func parseRequirement(value string) (requirement, error) {
+	pattern, owner, ok := strings.Cut(value, "=")
+	pattern, owner = strings.TrimSpace(pattern), strings.TrimSpace(owner)
+	if !ok || pattern == "" || owner == "" {
+		return requirement{}, fmt.Errorf("%q: expected pattern=owner", value)
+	}
+	rule, err := parsePattern(pattern)
+	if err != nil {
+		return requirement{}, fmt.Errorf("%q: %w", value, err)
+	}
+	return requirement{pattern: pattern, owner: owner, rule: rule}, nil
+}
+
+// parsePattern parses a single gitignore-style pattern the same way CODEOWNERS
+// patterns are parsed.
+func parsePattern(pattern string) (codeowners.Rule, error) {
+	rules, err := codeowners.ParseFile(strings.NewReader(pattern))
+	if err != nil {
+		return codeowners.Rule{}, err
+	}
+	if len(rules) != 1 {
+		return codeowners.Rule{}, fmt.Errorf("invalid pattern %q", pattern)
+	}
+	return rules[0], nil
+}
+
+// loadPatterns reads a file of gitignore-style patterns, one per line. Blank
+// lines and lines starting with # are ignored.
+func loadPatterns(path string) ([]codeowners.Rule, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []codeowners.Rule
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		rule, err := parsePattern(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+		rule.LineNumber = i + 1
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// matchesAny reports whether file matches any of the rules' patterns.
+func matchesAny(rules []codeowners.Rule, file string) bool {
+	return slices.ContainsFunc(rules, func(rule codeowners.Rule) bool {
+		match, _ := rule.Match(file)
+		return match
+	})
+}
+
+// suppressUnowned removes the unowned files matching the ignore patterns from
+// the report and returns how many were removed.
+func suppressUnowned(r *report.Report, ignore []codeowners.Rule) int {
+	kept := lo.Reject(r.Unowned, func(file string, _ int) bool {
+		return matchesAny(ignore, file)
+	})
+	suppressed := len(r.Unowned) - len(kept)
+	r.Unowned = kept
+	r.Stats.UnownedFiles = len(kept)
+	r.Stats.SuppressedUnowned = suppressed
+	return suppressed
+}
+
+// unownedViolations turns every unowned file into a violation.
+func unownedViolations(r report.Report) []report.Violation {
+	return lo.Map(r.Unowned, func(file string, _ int) report.Violation {
+		return report.Violation{
+			Rule:    report.RuleMissingCodeowner,
+			File:    file,
+			Message: file + " has no code owner",
+		}
+	})
+}
+
+// checkManifest determines for every manifest pattern how many files match
+// it and which of them are unowned.
+func checkManifest(manifest []codeowners.Rule, fileOwners map[string][]string) []report.ManifestEntry {
+	files := lo.Keys(fileOwners)
+	slices.Sort(files)
+	return lo.Map(manifest, func(rule codeowners.Rule, _ int) report.ManifestEntry {
+		entry := report.ManifestEntry{Pattern: rule.RawPattern(), Unowned: []string{}}
+		for _, file := range files {
+			if match, _ := rule.Match(file); !match {
+				continue
+			}
+			entry.Files++
+			if len(fileOwners[file]) == 0 {
+				entry.Unowned = append(entry.Unowned, file)
+			}
+		}
+		return entry
+	})
+}
+
+// manifestViolations turns every unowned file of a manifest entry into a
+// violation.
+func manifestViolations(entries []report.ManifestEntry) []report.Violation {
+	var violations []report.Violation
+	for _, entry := range entries {
+		for _, file := range entry.Unowned {
+			violations = append(violations, report.Violation{
+				Rule:    report.RuleCoverageManifest,
+				File:    file,
+				Message: fmt.Sprintf("%s has no code owner but matches %s, which must be owned", file, entry.Pattern),
+			})
+		}
+	}
+	return violations
+}
+
+// probeFile is the name of the made-up file checkDirCoverage matches. It has
+// no extension, so only rules covering all files of a directory match it.
+const probeFile = "codeownerreport-probe"
+
+// checkDirCoverage returns a violation for every directory with changed files
+// in which a file of any name wouldn't be owned, e.g. because the directory
+// is only covered by rules for certain extensions.
+func checkDirCoverage(fileOwners map[string][]string, sections []section, opts options) []report.Violation {
+	dirs := lo.Uniq(lo.Map(lo.Keys(fileOwners), func(file string, _ int) string { return path.Dir(file) }))
+	slices.Sort(dirs)
+	var violations []report.Violation
+	for _, dir := range dirs {
+		_, rules, _ := matchSections(sections, matchPath(path.Join(dir, probeFile), opts))
+		if slices.ContainsFunc(rules, func(rule *codeowners.Rule) bool { return len(rule.Owners) > 0 }) {
+			continue
+		}
+		name := dir + "/"
+		if dir == "." {
+			name = "/"
+		}
+		violations = append(violations, report.Violation{
+			Rule:    report.RuleDirCoverage,
+			File:    name,
+			Message: fmt.Sprintf("%s has no rule owning all of its files", name),
+		})
+	}
+	return violations
+}
+
+// checkRequirements returns a violation for every file that matches a
+// requirement without being owned by its owner. Owners are compared
+// case-insensitively, as GitHub does.
+func checkRequirements(requirements []requirement, fileOwners map[string][]string) []violation {
+	var violations []violation
+	files := lo.Keys(fileOwners)
+	slices.Sort(files)
+	for _, req := range requirements {
+		for _, file := range files {
+			if match, _ := req.rule.Match(file); !match {
+				continue
+			}
+			if !slices.ContainsFunc(fileOwners[file], func(owner string) bool { return strings.EqualFold(owner, req.owner) }) {
+				violations = append(violations, violation{file: file, requirement: req})
+			}
+		}
+	}
+	return violations
+}
+
+// unknownOwner is an owner assigned by a rule that is not in the list of known
+// owners, together with the changed files it was assigned to.
+type unknownOwner struct {
+	owner string
+	rule  *codeowners.Rule
+	files []string
+}
+
+func (u unknownOwner) report() []report.Violation {
+	return lo.Map(u.files, func(file string, _ int) report.Violation {
+		return report.Violation{
+			Rule:    report.RuleUnknownOwner,
+			File:    file,
+			Message: fmt.Sprintf("%s is owned by unknown owner %s (line %d)", file, u.owner, u.rule.LineNumber),
+		}
+	})
+}
+
+// loadKnownOwners reads a file with one owner per line. Blank lines and lines
+// starting with # are ignored. Without a path, nil is returned and all owners
+// are considered known.
+func loadKnownOwners(path string) (map[string]bool, error) {
+	if path == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]bool{}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		known[line] = true
+	}
+	return known, nil
+}
+
+// checkKnownOwners reports every owner of a matched rule that is not known.
+func checkKnownOwners(known map[string]bool, fileRules map[string]*codeowners.Rule) []unknownOwner {
+	if known == nil {
+		return nil
+	}
+	byKey := map[string]*unknownOwner{}
+	files := lo.Keys(fileRules)
+	slices.Sort(files)
+	for _, file := range files {
+		rule := fileRules[file]
+		for _, owner := range rule.Owners {
+			if known[owner.String()] {
+				continue
+			}
+			key := fmt.Sprintf("%s:%d", owner, rule.LineNumber)
+			if byKey[key] == nil {
+				byKey[key] = &unknownOwner{owner: owner.String(), rule: rule}
+			}
+			byKey[key].files = append(byKey[key].files, file)
+		}
+	}
+	unknown := lo.MapToSlice(byKey, func(_ string, u *unknownOwner) unknownOwner { return *u })
+	slices.SortFunc(unknown, func(a, b unknownOwner) int {
+		return cmp.Or(cmp.Compare(a.owner, b.owner), cmp.Compare(a.rule.LineNumber, b.rule.LineNumber))
+	})
+	return unknown
+}
+
+// mapErr maps values with fn, stopping at the first error.
+func mapErr[T, R any](values []T, fn func(T) (R, error)) ([]R, error) {
+	result := make([]R, 0, len(values))
+	for _, v := range values {
+		r, err := fn(v)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
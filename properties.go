@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+
+	"codeownerreport/report"
+)
+
+// writeProperties writes the report as Java properties, one owner.N=file entry
+// per file with N counting from 0. Unowned files use the key unowned.N, which
+// can't clash with an owner as those start with @ or are email addresses.
+func writeProperties(w io.Writer, r report.Report, owners []string) error {
+	write := func(key string, files []string) error {
+		for i, file := range files {
+			if _, err := fmt.Fprintf(w, "%s.%d=%s\n", escapeProperty(key, true), i, escapeProperty(file, false)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, owner := range owners {
+		if err := write(owner, r.Owners[owner]); err != nil {
+			return err
+		}
+	}
+	return write("unowned", r.Unowned)
+}
+
+// escapeProperty escapes s for use in a properties file as specified by
+// java.util.Properties. Characters outside of printable ASCII are written as
+// \uXXXX escapes, so the file can be read as ISO 8859-1.
+func escapeProperty(s string, key bool) string {
+	var b strings.Builder
+	for i, c := range s {
+		switch {
+		case c == '\\':
+			b.WriteString(`\\`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c == '\f':
+			b.WriteString(`\f`)
+		case c == ' ' && (key || i == 0), key && strings.ContainsRune("=:#!", c):
+			b.WriteRune('\\')
+			b.WriteRune(c)
+		case c < 0x20 || c > 0x7e:
+			for _, u := range utf16.Encode([]rune{c}) {
+				fmt.Fprintf(&b, `\u%04X`, u)
+			}
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"codeownerreport/report"
+)
+
+func TestEscapeProperty(t *testing.T) {
+	tests := []struct {
+		s    string
+		key  bool
+		want string
+	}{
+		{"a=b:c#d!e", true, `a\=b\:c\#d\!e`},
+		{"a=b:c#d!e", false, "a=b:c#d!e"},
+		{" lead", true, `\ lead`},
+		{" lead", false, `\ lead`},
+		{"a b", true, `a\ b`},
+		{"a b", false, "a b"},
+		{"tab\tnl\n", false, `tab\tnl\n`},
+		{`back\slash`, false, `back\\slash`},
+		{"grüße", false, `gr\u00FC\u00DFe`},
+		{"😀", false, `\uD83D\uDE00`},
+	}
+	for _, tt := range tests {
+		if got := escapeProperty(tt.s, tt.key); got != tt.want {
+			t.Errorf("escapeProperty(%q, %v) = %s, want %s", tt.s, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestWritePropertiesRoundTrip(t *testing.T) {
+	r := report.New(map[string][]string{
+		"a=b.txt":        {"@org/a:b"},
+		" leading.txt":   {"@org/#team"},
+		"dir/grüße.txt":  {"@org/a:b"},
+		"emoji/😀.md":     {"@org/#team"},
+		"unowned!=x.txt": nil,
+	})
+	var b strings.Builder
+	if err := writeProperties(&b, r, r.OwnerNames()); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range b.String() {
+		if c > 0x7e {
+			t.Fatalf("output contains non-ASCII %q:\n%s", c, b.String())
+		}
+	}
+
+	got := map[string][]string{}
+	scanner := bufio.NewScanner(strings.NewReader(b.String()))
+	for scanner.Scan() {
+		key, value := parsePropertyLine(t, scanner.Text())
+		owner, _, _ := cutLast(key, ".")
+		got[owner] = append(got[owner], value)
+	}
+	want := map[string][]string{"unowned": r.Unowned}
+	for owner, files := range r.Owners {
+		want[owner] = files
+	}
+	if len(got) != len(want) {
+		t.Fatalf("decoded %v, want %v", got, want)
+	}
+	for key, files := range want {
+		if strings.Join(got[key], "|") != strings.Join(files, "|") {
+			t.Errorf("%s: decoded %q, want %q", key, got[key], files)
+		}
+	}
+}
+
+// parsePropertyLine decodes a key=value line as java.util.Properties does.
+func parsePropertyLine(t *testing.T, line string) (string, string) {
+	t.Helper()
+	var key, value strings.Builder
+	out := &key
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '=' && out == &key:
+			out = &value
+		case c == '\\':
+			i++
+			switch line[i] {
+			case 't':
+				out.WriteByte('\t')
+			case 'n':
+				out.WriteByte('\n')
+			case 'r':
+				out.WriteByte('\r')
+			case 'f':
+				out.WriteByte('\f')
+			case 'u':
+				u, err := strconv.ParseUint(line[i+1:i+5], 16, 16)
+				if err != nil {
+					t.Fatalf("invalid escape in %q: %v", line, err)
+				}
+				i += 4
+				r := rune(u)
+				if utf16.IsSurrogate(r) && strings.HasPrefix(line[i+1:], `\u`) {
+					low, err := strconv.ParseUint(line[i+3:i+7], 16, 16)
+					if err != nil {
+						t.Fatalf("invalid escape in %q: %v", line, err)
+					}
+					i += 6
+					r = utf16.DecodeRune(r, rune(low))
+				}
+				out.WriteRune(r)
+			default:
+				out.WriteByte(line[i])
+			}
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return key.String(), value.String()
+}
+
+func cutLast(s, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
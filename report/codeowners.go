@@ -0,0 +1,76 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ReadCodeowners reads the CODEOWNERS file at location, which is either a local
+// path or an HTTP(S) URL. A missing file is reported as ErrNoCodeowners.
+// Symlinked files, e.g. a CODEOWNERS file shared between repositories, are
+// followed and the resolved path is logged.
+func ReadCodeowners(location string) ([]byte, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return readCodeownersFile(location)
+	}
+
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: fetching %s: unexpected status %s", ErrNoCodeowners, location, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: unexpected status %s", location, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func readCodeownersFile(path string) ([]byte, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if target, linkErr := os.Readlink(path); linkErr == nil {
+			return nil, fmt.Errorf("%w: %s is a symlink to %s, which does not exist", ErrNoCodeowners, path, target)
+		}
+		return nil, fmt.Errorf("%w: %w", ErrNoCodeowners, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(resolved); err == nil && info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory, not a CODEOWNERS file", path)
+	}
+	if resolved != filepath.Clean(path) {
+		slog.Info("Following symlinked CODEOWNERS.", "path", path, "resolved", resolved)
+	}
+	return os.ReadFile(resolved)
+}
+
+// ReadCodeownersAt reads the CODEOWNERS file at path, relative to the
+// repository root, from the tree of commit. A missing file is reported as
+// ErrNoCodeowners.
+func ReadCodeownersAt(commit *object.Commit, path string) ([]byte, error) {
+	file, err := commit.File(path)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, fmt.Errorf("%w: %s in commit %s", ErrNoCodeowners, path, commit.Hash)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s from commit %s: %w", path, commit.Hash, err)
+	}
+	content, err := file.Contents()
+	return []byte(content), err
+}
@@ -0,0 +1,74 @@
+package report
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadCodeownersSymlink(t *testing.T) {
+	dir := t.TempDir()
+	shared := filepath.Join(dir, "shared", "CODEOWNERS")
+	if err := os.MkdirAll(filepath.Dir(shared), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(shared, []byte("* @org/default\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "CODEOWNERS")
+	if err := os.Symlink(filepath.Join("shared", "CODEOWNERS"), link); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	content, err := ReadCodeowners(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "* @org/default\n" {
+		t.Errorf("ReadCodeowners = %q, want the content of the link target", content)
+	}
+}
+
+func TestReadCodeownersDanglingSymlink(t *testing.T) {
+	link := filepath.Join(t.TempDir(), "CODEOWNERS")
+	if err := os.Symlink("missing/CODEOWNERS", link); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	_, err := ReadCodeowners(link)
+	if !errors.Is(err, ErrNoCodeowners) {
+		t.Fatalf("ReadCodeowners = %v, want ErrNoCodeowners", err)
+	}
+	if !strings.Contains(err.Error(), "is a symlink to missing/CODEOWNERS, which does not exist") {
+		t.Errorf("error %q doesn't name the missing link target", err)
+	}
+}
+
+func TestReadCodeownersDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "CODEOWNERS")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ReadCodeowners(dir)
+	if err == nil || !strings.Contains(err.Error(), "is a directory, not a CODEOWNERS file") {
+		t.Errorf("ReadCodeowners = %v, want an error saying it is a directory", err)
+	}
+	if errors.Is(err, ErrNoCodeowners) {
+		t.Error("a directory is reported as a missing CODEOWNERS file")
+	}
+}
+
+func TestReadCodeownersEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CODEOWNERS")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ReadCodeowners(path)
+	if err != nil || len(content) != 0 {
+		t.Errorf("ReadCodeowners = %q, %v, want empty content", content, err)
+	}
+}
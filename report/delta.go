@@ -0,0 +1,90 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+
+	"github.com/samber/lo"
+)
+
+// Delta describes how ownership changed between two reports.
+type Delta struct {
+	// NewlyUnowned lists files that are unowned now but weren't before,
+	// either because they were owned or because they weren't part of the
+	// previous report.
+	NewlyUnowned []string `json:"newly_unowned"`
+	// AddedOwners lists owners that are new to the report.
+	AddedOwners []string `json:"added_owners"`
+	// RemovedOwners lists owners that are no longer part of the report.
+	RemovedOwners []string `json:"removed_owners"`
+	// Changed lists files present in both reports whose owners differ.
+	Changed []OwnershipChange `json:"changed"`
+}
+
+// OwnershipChange describes a file whose owners changed.
+type OwnershipChange struct {
+	File   string   `json:"file"`
+	Before []string `json:"before"`
+	After  []string `json:"after"`
+}
+
+// Empty reports whether nothing changed.
+func (d Delta) Empty() bool {
+	return len(d.NewlyUnowned) == 0 && len(d.AddedOwners) == 0 && len(d.RemovedOwners) == 0 && len(d.Changed) == 0
+}
+
+// ReadJSON reads a report previously written with WriteJSON.
+func ReadJSON(r io.Reader) (Report, error) {
+	var rep Report
+	err := json.NewDecoder(r).Decode(&rep)
+	return rep, err
+}
+
+// Compare determines what changed from prev to cur.
+func Compare(prev, cur Report) Delta {
+	d := Delta{
+		NewlyUnowned:  lo.Without(cur.Unowned, prev.Unowned...),
+		AddedOwners:   lo.Without(cur.OwnerNames(), prev.OwnerNames()...),
+		RemovedOwners: lo.Without(prev.OwnerNames(), cur.OwnerNames()...),
+		Changed:       []OwnershipChange{},
+	}
+
+	before, after := prev.FileOwners(), cur.FileOwners()
+	files := lo.Keys(after)
+	slices.Sort(files)
+	for _, file := range files {
+		prevOwners, ok := before[file]
+		if !ok || slices.Equal(prevOwners, after[file]) {
+			continue
+		}
+		d.Changed = append(d.Changed, OwnershipChange{File: file, Before: prevOwners, After: after[file]})
+	}
+	return d
+}
+
+// FileOwners inverts the report into a mapping of every file to its sorted
+// owners. Unowned files map to an empty list.
+func (r Report) FileOwners() map[string][]string {
+	fileOwners := map[string][]string{}
+	for _, file := range r.Unowned {
+		fileOwners[file] = []string{}
+	}
+	for _, owner := range r.OwnerNames() {
+		for _, file := range r.Owners[owner] {
+			fileOwners[file] = append(fileOwners[file], owner)
+		}
+	}
+	return fileOwners
+}
+
+// FileOwnerships lists every file with its change type and sorted owners,
+// sorted by path.
+func (r Report) FileOwnerships() []FileOwnership {
+	fileOwners := r.FileOwners()
+	files := lo.Keys(fileOwners)
+	slices.Sort(files)
+	return lo.Map(files, func(file string, _ int) FileOwnership {
+		return FileOwnership{File: file, Change: r.Changes[file], Owners: fileOwners[file]}
+	})
+}
@@ -0,0 +1,16 @@
+package report
+
+import "errors"
+
+var (
+	// ErrNoCodeowners is returned when no CODEOWNERS file can be found.
+	ErrNoCodeowners = errors.New("no CODEOWNERS file found")
+	// ErrDetachedHead is returned when HEAD does not point to a branch.
+	ErrDetachedHead = errors.New("HEAD is not on a branch")
+	// ErrNoBaseBranch is returned when no base was given and neither a main
+	// nor a master branch exists.
+	ErrNoBaseBranch = errors.New("no main or master branch found")
+	// ErrNoMergeBase is returned when the current branch and the base share
+	// no history.
+	ErrNoMergeBase = errors.New("no merge base found")
+)
@@ -0,0 +1,431 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Change describes the change from the merge base with the base branch to the
+// tip of the current branch.
+type Change struct {
+	// CurrentBranch is the short name of the checked out branch.
+	CurrentBranch string
+	// BaseName is the name of the base the branch is compared against.
+	BaseName string
+	// BaseCommit is the commit the change is compared against: the merge
+	// base of the current branch and the base, or the tip of the base in
+	// two-dot and merge mode. It is nil when reporting on a root commit.
+	BaseCommit *object.Commit
+	// HeadCommit is the commit whose changes are reported.
+	HeadCommit *object.Commit
+	// Files maps the paths touched by the change to how they were changed,
+	// with one entry per path. Files moved without changes are listed as
+	// Renamed under both their old and new path. Renamed files that were also
+	// edited are listed as Deleted and Added, copies as Added.
+	Files map[string]ChangeType
+	// Behind reports whether the head is an ancestor of the tip of the base,
+	// i.e. the branch has no commits of its own and lags behind the base.
+	Behind bool
+}
+
+// ChangeType describes how a file was changed, using the letters of
+// git diff --name-status.
+type ChangeType string
+
+const (
+	Added    ChangeType = "A"
+	Modified ChangeType = "M"
+	Deleted  ChangeType = "D"
+	Renamed  ChangeType = "R"
+)
+
+// DiffMode selects which commits the current branch is diffed against,
+// following the two revision range notations of git diff.
+type DiffMode string
+
+const (
+	// ThreeDot diffs against the merge base with the base (base...HEAD),
+	// reporting only what changed on the current branch.
+	ThreeDot DiffMode = "three-dot"
+	// TwoDot diffs against the tip of the base (base..HEAD), so changes
+	// made on the base since the branch was created are reported, too.
+	TwoDot DiffMode = "two-dot"
+	// Merge reports the files a merge of the current branch into the base
+	// would change on the base. Unlike ThreeDot, files the base already
+	// contains in the same version are left out.
+	Merge DiffMode = "merge"
+)
+
+// ChangeOptions control how LoadChange determines the change.
+type ChangeOptions struct {
+	// Base is the revision to compare against. See ResolveBase for the
+	// defaults.
+	Base string
+	// FirstParent only considers the first-parent history of the base when
+	// looking for the merge base.
+	FirstParent bool
+	// Commit, if set, reports on the changes of this single commit compared
+	// to its first parent, instead of on the current branch.
+	Commit string
+	// Retries is how often reading tree objects and computing the diff is
+	// retried after transient errors, e.g. on flaky network filesystems.
+	Retries int
+	// DiffMode defaults to ThreeDot.
+	DiffMode DiffMode
+	// UseRemoteDefault uses the default branch of origin, as recorded in
+	// refs/remotes/origin/HEAD, as the base when no explicit Base is given.
+	// If origin/HEAD is not set, the base is resolved as usual.
+	UseRemoteDefault bool
+	// ExcludeMerges only reports the files changed by the non-merge commits
+	// of the current branch, so that changes brought in by merges, e.g. of
+	// the base into the branch, don't count. It has no effect in TwoDot and
+	// Merge mode.
+	ExcludeMerges bool
+}
+
+// LoadChange determines the files changed on the current branch of the
+// repository at path.
+func LoadChange(path string, opts ChangeOptions) (Change, error) {
+	repo, err := OpenRepository(path)
+	if err != nil {
+		return Change{}, fmt.Errorf("opening repository: %w", err)
+	}
+
+	if opts.Commit != "" {
+		change, err := loadCommitChange(repo, opts.Commit, opts.Retries)
+		if err == nil {
+			slog.Info("Selected commit.", "commit", change.HeadCommit.Hash)
+		}
+		return change, err
+	}
+
+	currentBranch, err := repo.Head()
+	if err != nil {
+		return Change{}, fmt.Errorf("getting current branch: %w", err)
+	}
+	if !currentBranch.Name().IsBranch() {
+		return Change{}, ErrDetachedHead
+	}
+	slog.Info("Selected current branch.", "branch", currentBranch.Name().Short())
+
+	base := opts.Base
+	if base == "" && opts.UseRemoteDefault {
+		if base, err = RemoteDefaultBranch(repo); err != nil {
+			slog.Warn("Remote default branch unknown, falling back to main or master.", "error", err)
+		}
+	}
+
+	baseName, mainCommit, err := ResolveBase(repo, base)
+	if err != nil {
+		return Change{}, fmt.Errorf("resolving base: %w", err)
+	}
+
+	slog.Info("Selected reference branch.", "branch", baseName)
+
+	currentCommit, err := repo.CommitObject(currentBranch.Hash())
+	if err != nil {
+		return Change{}, fmt.Errorf("resolving HEAD commit: %w", err)
+	}
+
+	var baseCommits []*object.Commit
+	switch {
+	case opts.DiffMode == TwoDot:
+		baseCommits = []*object.Commit{mainCommit}
+	case opts.FirstParent:
+		baseCommits, err = firstParentBase(mainCommit, currentCommit)
+	default:
+		baseCommits, err = currentCommit.MergeBase(mainCommit)
+	}
+	if err != nil {
+		return Change{}, fmt.Errorf("resolving merge base commit: %w", err)
+	}
+
+	if len(baseCommits) < 1 {
+		return Change{}, ErrNoMergeBase
+	}
+
+	baseCommit := baseCommits[0]
+
+	behind := false
+	switch {
+	case currentCommit.Hash == mainCommit.Hash:
+	case opts.DiffMode != TwoDot && !opts.FirstParent:
+		// The merge base is HEAD itself exactly when HEAD is an ancestor of
+		// the base, which saves walking the history of the base.
+		behind = baseCommit.Hash == currentCommit.Hash
+	default:
+		if behind, err = currentCommit.IsAncestor(mainCommit); err != nil {
+			return Change{}, fmt.Errorf("checking whether HEAD is behind the base: %w", err)
+		}
+	}
+
+	var files map[string]ChangeType
+	if opts.DiffMode == Merge {
+		files, err = mergeChangedFiles(baseCommit, mainCommit, currentCommit, opts.Retries)
+		baseCommit = mainCommit
+	} else if opts.ExcludeMerges && opts.DiffMode != TwoDot {
+		files, err = authoredFiles(baseCommit, currentCommit, opts.Retries)
+	} else {
+		files, err = changedFiles(baseCommit, currentCommit, opts.Retries)
+	}
+	if err != nil {
+		return Change{}, err
+	}
+
+	return Change{
+		CurrentBranch: currentBranch.Name().Short(),
+		BaseName:      baseName,
+		BaseCommit:    baseCommit,
+		HeadCommit:    currentCommit,
+		Files:         files,
+		Behind:        behind,
+	}, nil
+}
+
+// OpenRepository opens the repository at path. Linked worktrees (created with
+// git worktree add) are supported: their refs and objects are read from the
+// common directory of the main checkout.
+func OpenRepository(path string) (*git.Repository, error) {
+	return git.PlainOpenWithOptions(path, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+}
+
+// loadCommitChange determines the files changed by a single commit compared
+// to its first parent. All files of a root commit count as added.
+func loadCommitChange(repo *git.Repository, rev string, retries int) (Change, error) {
+	commit, err := ResolveCommit(repo, rev)
+	if err != nil {
+		return Change{}, err
+	}
+
+	var parent *object.Commit
+	if commit.NumParents() > 0 {
+		if parent, err = commit.Parent(0); err != nil {
+			return Change{}, fmt.Errorf("resolving parent commit: %w", err)
+		}
+	}
+
+	files, err := changedFiles(parent, commit, retries)
+	if err != nil {
+		return Change{}, err
+	}
+	return Change{
+		BaseName:   rev + "^",
+		BaseCommit: parent,
+		HeadCommit: commit,
+		Files:      files,
+	}, nil
+}
+
+// changedFiles returns the paths that differ between the trees of two commits
+// and how they changed. A nil base is treated as an empty tree. Object access
+// is retried up to retries times.
+func changedFiles(base, head *object.Commit, retries int) (map[string]ChangeType, error) {
+	var baseTree *object.Tree
+	if base != nil {
+		var err error
+		if baseTree, err = withRetries(retries, "base tree", base.Tree); err != nil {
+			return nil, fmt.Errorf("getting base commit tree: %w", err)
+		}
+	}
+
+	currentTree, err := withRetries(retries, "current tree", head.Tree)
+	if err != nil {
+		return nil, fmt.Errorf("getting current commit tree: %w", err)
+	}
+
+	// Only exact renames are detected, which compares blob hashes. Finding
+	// renamed and edited files would require reading the blobs.
+	diff, err := withRetries(retries, "diff", func() (object.Changes, error) {
+		return object.DiffTreeWithOptions(context.Background(), baseTree, currentTree, &object.DiffTreeOptions{
+			DetectRenames:    true,
+			OnlyExactRenames: true,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("determining diff between trees: %w", err)
+	}
+
+	// Only the paths are needed, so the changes are walked directly instead
+	// of generating a patch, which would read and diff every blob.
+	files := map[string]ChangeType{}
+	for _, change := range diff {
+		switch {
+		case change.From.Name == "":
+			files[change.To.Name] = Added
+		case change.To.Name == "":
+			files[change.From.Name] = Deleted
+		case change.From.Name != change.To.Name:
+			files[change.From.Name] = Renamed
+			files[change.To.Name] = Renamed
+		default:
+			files[change.To.Name] = Modified
+		}
+	}
+	return files, nil
+}
+
+// definitiveErrors are errors that retrying can't fix.
+var definitiveErrors = []error{
+	git.ErrBranchNotFound,
+	plumbing.ErrReferenceNotFound,
+	plumbing.ErrObjectNotFound,
+}
+
+// withRetries calls fn until it succeeds, returns a definitive error or has
+// been retried retries times. The delay between attempts doubles, starting at
+// 100ms.
+func withRetries[T any](retries int, what string, fn func() (T, error)) (T, error) {
+	delay := 100 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		result, err := fn()
+		if err == nil || attempt > retries || slices.ContainsFunc(definitiveErrors, func(target error) bool {
+			return errors.Is(err, target)
+		}) {
+			return result, err
+		}
+		slog.Debug("Retrying git object access.", "operation", what, "attempt", attempt, "delay", delay, "error", err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// baseEnvVars are the environment variables CI systems use to announce the
+// target branch of a pull or merge request, in order of precedence.
+var baseEnvVars = []string{"GITHUB_BASE_REF", "CI_MERGE_REQUEST_TARGET_BRANCH_NAME"}
+
+// ResolveBase returns the name and commit of the base to compare against. In
+// order of precedence, the base is:
+//
+//   - the explicit base, which may be any revision, including annotated tags
+//     (peeled to their commit) and refs that only exist in packed-refs,
+//   - the branch named by one of baseEnvVars,
+//   - the upstream of the main or master branch,
+//   - the local main or master branch.
+func ResolveBase(repo *git.Repository, base string) (string, *object.Commit, error) {
+	if base != "" {
+		commit, err := ResolveCommit(repo, base)
+		return base, commit, err
+	}
+
+	for _, name := range baseEnvVars {
+		if branch := os.Getenv(name); branch != "" {
+			slog.Info("Using base branch from environment.", "variable", name)
+			commit, err := resolveBranch(repo, branch)
+			return branch, commit, err
+		}
+	}
+
+	mainBranch, err := repo.Branch("main")
+	if errors.Is(err, git.ErrBranchNotFound) {
+		mainBranch, err = repo.Branch("master")
+	}
+	if errors.Is(err, git.ErrBranchNotFound) {
+		return resolveLocalMain(repo)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("finding main branch: %w", err)
+	}
+
+	mainRef, err := repo.Reference(mainBranch.Merge, true)
+	if err != nil {
+		return "", nil, fmt.Errorf("resolving main branch to reference: %w", err)
+	}
+	mainCommit, err := repo.CommitObject(mainRef.Hash())
+	if err != nil {
+		return "", nil, fmt.Errorf("resolving main branch to commit: %w", err)
+	}
+	return mainBranch.Name, mainCommit, nil
+}
+
+// RemoteDefaultBranch returns the default branch of origin, e.g.
+// "origin/main", as recorded in refs/remotes/origin/HEAD by git clone or
+// git remote set-head.
+func RemoteDefaultBranch(repo *git.Repository) (string, error) {
+	ref, err := repo.Reference(plumbing.NewRemoteHEADReferenceName("origin"), false)
+	if err != nil {
+		return "", fmt.Errorf("resolving origin/HEAD: %w", err)
+	}
+	if ref.Type() != plumbing.SymbolicReference {
+		return "", errors.New("origin/HEAD is not a symbolic reference")
+	}
+	return ref.Target().Short(), nil
+}
+
+// ResolveCommit resolves a revision to the commit it points to.
+func ResolveCommit(repo *git.Repository, rev string) (*object.Commit, error) {
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("resolving %q: %w", rev, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %q to commit: %w", rev, err)
+	}
+	return commit, nil
+}
+
+// resolveBranch resolves a branch name, preferring the remote-tracking branch
+// of origin over the local branch. CI checkouts often only have the former.
+func resolveBranch(repo *git.Repository, branch string) (*object.Commit, error) {
+	ref, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", branch), true)
+	if err == nil {
+		return repo.CommitObject(ref.Hash())
+	}
+	return ResolveCommit(repo, branch)
+}
+
+// resolveLocalMain resolves the local main or master branch, for repositories
+// where neither has an upstream configured.
+func resolveLocalMain(repo *git.Repository) (string, *object.Commit, error) {
+	for _, name := range []string{"main", "master"} {
+		ref, err := repo.Reference(plumbing.NewBranchReferenceName(name), true)
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("resolving %s branch: %w", name, err)
+		}
+		commit, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return "", nil, fmt.Errorf("resolving %s branch to commit: %w", name, err)
+		}
+		return name, commit, nil
+	}
+	return "", nil, ErrNoBaseBranch
+}
+
+// firstParentBase walks the first-parent history of tip, like
+// git log --first-parent does, and returns the first commit that is also part
+// of head's history. Unlike a merge base, the result always lies on the
+// mainline of tip, never on a side branch that was merged into it. Head's
+// history is collected once, so each commit of the walk is a lookup.
+func firstParentBase(tip, head *object.Commit) ([]*object.Commit, error) {
+	ancestors := map[plumbing.Hash]bool{}
+	err := object.NewCommitPreorderIter(head, nil, nil).ForEach(func(c *object.Commit) error {
+		ancestors[c.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for c := tip; ; {
+		if ancestors[c.Hash] {
+			return []*object.Commit{c}, nil
+		}
+		if c.NumParents() == 0 {
+			return nil, nil
+		}
+		if c, err = c.Parent(0); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package report
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+var testSignature = &object.Signature{Name: "Test", Email: "test@example.com", When: time.Unix(1700000000, 0)}
+
+// initRepo creates a repository in a temporary directory with main checked
+// out.
+func initRepo(t testing.TB) (*git.Repository, string) {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := git.PlainInitWithOptions(dir, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName("main")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo, dir
+}
+
+// commitFiles writes files to the working tree of repo, removing those with
+// nil content, and commits all changes.
+func commitFiles(t testing.TB, repo *git.Repository, dir string, files map[string][]byte) *object.Commit {
+	t.Helper()
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if content == nil {
+			if _, err := wt.Remove(name); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wt.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash, err := wt.Commit("commit", &git.CommitOptions{Author: testSignature, AllowEmptyCommits: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return commit
+}
+
+func TestResolveBaseAnnotatedTag(t *testing.T) {
+	repo, dir := initRepo(t)
+	commit := commitFiles(t, repo, dir, map[string][]byte{"a": []byte("a")})
+	_, err := repo.CreateTag("v1", commit.Hash, &git.CreateTagOptions{Tagger: testSignature, Message: "v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, base, err := ResolveBase(repo, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "v1" || base.Hash != commit.Hash {
+		t.Errorf("ResolveBase(v1) = %s %s, want v1 %s", name, base.Hash, commit.Hash)
+	}
+}
+
+func TestResolveBasePackedRef(t *testing.T) {
+	repo, dir := initRepo(t)
+	commit := commitFiles(t, repo, dir, map[string][]byte{"a": []byte("a")})
+	packed := "# pack-refs with: peeled fully-peeled sorted\n" + commit.Hash.String() + " refs/heads/packed\n"
+	if err := os.WriteFile(filepath.Join(dir, ".git", "packed-refs"), []byte(packed), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := OpenRepository(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rev := range []string{"packed", "refs/heads/packed"} {
+		_, base, err := ResolveBase(repo, rev)
+		if err != nil {
+			t.Fatalf("ResolveBase(%s): %v", rev, err)
+		}
+		if base.Hash != commit.Hash {
+			t.Errorf("ResolveBase(%s) = %s, want %s", rev, base.Hash, commit.Hash)
+		}
+	}
+}
+
+func TestLoadChangeLinkedWorktree(t *testing.T) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git not installed")
+	}
+	repo, dir := initRepo(t)
+	commitFiles(t, repo, dir, map[string][]byte{"a": []byte("a")})
+
+	worktree := filepath.Join(t.TempDir(), "wt")
+	runGit := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command(gitPath, args...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	runGit("-C", dir, "worktree", "add", "-b", "feature", worktree)
+	if err := os.WriteFile(filepath.Join(worktree, "b"), []byte("b"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit("-C", worktree, "add", "b")
+	runGit("-C", worktree, "commit", "-m", "b")
+
+	for _, name := range baseEnvVars {
+		t.Setenv(name, "")
+	}
+	change, err := LoadChange(worktree, ChangeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.CurrentBranch != "feature" || change.BaseName != "main" {
+		t.Errorf("compared %s against %s, want feature against main", change.CurrentBranch, change.BaseName)
+	}
+	if want := map[string]ChangeType{"b": Added}; !maps.Equal(change.Files, want) {
+		t.Errorf("Files = %v, want %v", change.Files, want)
+	}
+}
+
+func TestLoadChangeBehind(t *testing.T) {
+	repo, dir := initRepo(t)
+	fork := commitFiles(t, repo, dir, map[string][]byte{"a": []byte("a")})
+	commitFiles(t, repo, dir, map[string][]byte{"b": []byte("b")})
+	feature := plumbing.NewBranchReferenceName("feature")
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(feature, fork.Hash)); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, feature)); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range baseEnvVars {
+		t.Setenv(name, "")
+	}
+
+	modes := []ChangeOptions{
+		{}, {DiffMode: TwoDot}, {DiffMode: Merge}, {FirstParent: true},
+	}
+	for _, opts := range modes {
+		change, err := LoadChange(dir, opts)
+		if err != nil {
+			t.Fatalf("%+v: %v", opts, err)
+		}
+		if !change.Behind {
+			t.Errorf("%+v: feature at the fork point isn't behind main", opts)
+		}
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.Checkout(&git.CheckoutOptions{Branch: feature, Force: true}); err != nil {
+		t.Fatal(err)
+	}
+	commitFiles(t, repo, dir, map[string][]byte{"c": []byte("c")})
+	for _, opts := range modes {
+		change, err := LoadChange(dir, opts)
+		if err != nil {
+			t.Fatalf("%+v: %v", opts, err)
+		}
+		if change.Behind {
+			t.Errorf("%+v: feature with a commit of its own is behind main", opts)
+		}
+	}
+}
+
+// BenchmarkChangedFiles compares collecting the paths of a one-file change
+// from the tree changes, as changedFiles does, with generating the textual
+// patch, which reads and diffs the blobs.
+func BenchmarkChangedFiles(b *testing.B) {
+	repo, dir := initRepo(b)
+	files := map[string][]byte{}
+	for i := range 200 {
+		files[fmt.Sprintf("dir%d/file%d.txt", i%10, i)] = []byte(strings.Repeat(fmt.Sprintf("line %d\n", i), 500))
+	}
+	base := commitFiles(b, repo, dir, files)
+	head := commitFiles(b, repo, dir, map[string][]byte{"dir3/file3.txt": []byte(strings.Repeat("changed\n", 500))})
+
+	b.Run("tree-changes", func(b *testing.B) {
+		for range b.N {
+			if _, err := changedFiles(base, head, 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("patch", func(b *testing.B) {
+		for range b.N {
+			patch, err := base.Patch(head)
+			if err != nil {
+				b.Fatal(err)
+			}
+			paths := map[string]bool{}
+			for _, fp := range patch.FilePatches() {
+				from, to := fp.Files()
+				if from != nil {
+					paths[from.Path()] = true
+				}
+				if to != nil {
+					paths[to.Path()] = true
+				}
+			}
+		}
+	})
+}
+
+func TestChangedFiles(t *testing.T) {
+	content := []byte(strings.Repeat("some content\n", 20))
+	tests := []struct {
+		name  string
+		files map[string][]byte
+		want  map[string]ChangeType
+	}{
+		{
+			name:  "modify",
+			files: map[string][]byte{"a.txt": []byte("changed")},
+			want:  map[string]ChangeType{"a.txt": Modified},
+		},
+		{
+			name:  "rename",
+			files: map[string][]byte{"b.txt": nil, "moved/b.txt": content},
+			want:  map[string]ChangeType{"b.txt": Renamed, "moved/b.txt": Renamed},
+		},
+		{
+			name:  "rename and edit",
+			files: map[string][]byte{"b.txt": nil, "moved/b.txt": append([]byte("edited\n"), content...)},
+			want:  map[string]ChangeType{"b.txt": Deleted, "moved/b.txt": Added},
+		},
+		{
+			name:  "copy",
+			files: map[string][]byte{"copy/b.txt": content},
+			want:  map[string]ChangeType{"copy/b.txt": Added},
+		},
+		{
+			name:  "add and delete",
+			files: map[string][]byte{"a.txt": nil, "new.txt": []byte("new")},
+			want:  map[string]ChangeType{"a.txt": Deleted, "new.txt": Added},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, dir := initRepo(t)
+			base := commitFiles(t, repo, dir, map[string][]byte{"a.txt": []byte("a"), "b.txt": content})
+			head := commitFiles(t, repo, dir, tt.files)
+
+			files, err := changedFiles(base, head, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(files, tt.want) {
+				t.Errorf("changedFiles = %v, want %v", files, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangedFilesRootCommit(t *testing.T) {
+	repo, dir := initRepo(t)
+	head := commitFiles(t, repo, dir, map[string][]byte{"a.txt": []byte("a")})
+
+	files, err := changedFiles(nil, head, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]ChangeType{"a.txt": Added}; !maps.Equal(files, want) {
+		t.Errorf("changedFiles = %v, want %v", files, want)
+	}
+}
+
+// commitParents stores a commit with the tree of tree and the given parents.
+// Commits are told apart by their message.
+func commitParents(t testing.TB, repo *git.Repository, message string, tree *object.Commit, parents ...*object.Commit) *object.Commit {
+	t.Helper()
+	commit := &object.Commit{Author: *testSignature, Committer: *testSignature, Message: message, TreeHash: tree.TreeHash}
+	for _, parent := range parents {
+		commit.ParentHashes = append(commit.ParentHashes, parent.Hash)
+	}
+	obj := repo.Storer.NewEncodedObject()
+	if err := commit.Encode(obj); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err = repo.CommitObject(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return commit
+}
+
+func TestFirstParentBase(t *testing.T) {
+	repo, dir := initRepo(t)
+	root := commitFiles(t, repo, dir, map[string][]byte{"a": []byte("a")})
+	mainline := commitParents(t, repo, "mainline", root, root)
+	side := commitParents(t, repo, "side", root, root)
+	merge := commitParents(t, repo, "merge", root, mainline, side)
+	head := commitParents(t, repo, "head", root, side)
+
+	base, err := firstParentBase(merge, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The merge base would be side, which was merged into the mainline.
+	if len(base) != 1 || base[0].Hash != root.Hash {
+		t.Errorf("firstParentBase = %v, want the root commit %s", base, root.Hash)
+	}
+
+	base, err = firstParentBase(commitParents(t, repo, "unrelated", root), head)
+	if err != nil || len(base) != 0 {
+		t.Errorf("firstParentBase of unrelated history = %v, %v, want none", base, err)
+	}
+}
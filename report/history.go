@@ -0,0 +1,48 @@
+package report
+
+import (
+	"fmt"
+	"time"
+)
+
+// CommitOwnership counts the files each owner was responsible for in the
+// changes of a single commit.
+type CommitOwnership struct {
+	Commit string    `json:"commit"`
+	Time   time.Time `json:"time"`
+	// Owners maps each owner to the number of changed files it owns.
+	Owners map[string]int `json:"owners"`
+	// Unowned is the number of changed files without an owner.
+	Unowned int `json:"unowned"`
+}
+
+// LoadHistory returns the changes of the last n commits on the first-parent
+// history of HEAD in the repository at path, newest first, each compared to
+// its first parent. With excludeMerges, merge commits are skipped and don't
+// count towards n. Fewer changes are returned if the history is shorter.
+func LoadHistory(path string, n int, retries int, excludeMerges bool) ([]Change, error) {
+	repo, err := OpenRepository(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening repository: %w", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("resolving HEAD: %w", err)
+	}
+
+	var changes []Change
+	for rev := head.Hash().String(); len(changes) < n; {
+		change, err := loadCommitChange(repo, rev, retries)
+		if err != nil {
+			return nil, err
+		}
+		if !excludeMerges || change.HeadCommit.NumParents() < 2 {
+			changes = append(changes, change)
+		}
+		if change.BaseCommit == nil {
+			break
+		}
+		rev = change.BaseCommit.Hash.String()
+	}
+	return changes, nil
+}
@@ -0,0 +1,85 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/samber/lo"
+)
+
+// mergeChangedFiles simulates merging head into tip, given their merge base,
+// and returns the files the merge would change on tip. These are the files
+// changed on the branch, except those that tip already has in the same
+// version. Files changed differently on both sides would conflict; they are
+// reported as changed with a warning, since their merged content depends on
+// how the conflict is resolved.
+func mergeChangedFiles(mergeBase, tip, head *object.Commit, retries int) (map[string]ChangeType, error) {
+	branchFiles, err := changedFiles(mergeBase, head, retries)
+	if err != nil {
+		return nil, err
+	}
+	baseFiles, err := changedFiles(mergeBase, tip, retries)
+	if err != nil {
+		return nil, err
+	}
+	tipTree, err := withRetries(retries, "base tree", tip.Tree)
+	if err != nil {
+		return nil, fmt.Errorf("getting base commit tree: %w", err)
+	}
+	headTree, err := withRetries(retries, "current tree", head.Tree)
+	if err != nil {
+		return nil, fmt.Errorf("getting current commit tree: %w", err)
+	}
+
+	files := map[string]ChangeType{}
+	var conflicts []string
+	for file, changeType := range branchFiles {
+		tipHash, err := blobHash(tipTree, file)
+		if err != nil {
+			return nil, err
+		}
+		headHash, err := blobHash(headTree, file)
+		if err != nil {
+			return nil, err
+		}
+		if tipHash == headHash {
+			continue
+		}
+		if _, ok := baseFiles[file]; ok {
+			conflicts = append(conflicts, file)
+			switch {
+			case tipHash.IsZero():
+				changeType = Added
+			case headHash.IsZero():
+				changeType = Deleted
+			default:
+				changeType = Modified
+			}
+		}
+		files[file] = changeType
+	}
+	if len(conflicts) > 0 {
+		slices.Sort(conflicts)
+		slog.Warn("Files changed on both branches would conflict when merging.", "files", conflicts)
+	}
+	return files, nil
+}
+
+// blobHash returns the hash of the file at path in tree, or the zero hash if
+// the tree has no such file.
+func blobHash(tree *object.Tree, path string) (plumbing.Hash, error) {
+	entry, err := tree.FindEntry(path)
+	if lo.ContainsBy([]error{object.ErrEntryNotFound, object.ErrDirectoryNotFound, object.ErrFileNotFound}, func(target error) bool {
+		return errors.Is(err, target)
+	}) {
+		return plumbing.ZeroHash, nil
+	}
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("looking up %s: %w", path, err)
+	}
+	return entry.Hash, nil
+}
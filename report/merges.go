@@ -0,0 +1,60 @@
+package report
+
+import (
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// authoredFiles returns the files changed by the non-merge commits reachable
+// from head but not from base, each compared to its parent. Files whose
+// changes only stem from merge commits, e.g. from merging the base into the
+// branch, are left out. Change types are taken from the diff between base and
+// head where the file differs, and from the newest commit touching it
+// otherwise.
+func authoredFiles(base, head *object.Commit, retries int) (map[string]ChangeType, error) {
+	net, err := changedFiles(base, head, retries)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[plumbing.Hash]bool{}
+	if base != nil {
+		err := object.NewCommitPreorderIter(base, nil, nil).ForEach(func(c *object.Commit) error {
+			seen[c.Hash] = true
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	files := map[string]ChangeType{}
+	err = object.NewCommitPreorderIter(head, seen, nil).ForEach(func(c *object.Commit) error {
+		if c.NumParents() > 1 {
+			return nil
+		}
+		var parent *object.Commit
+		if c.NumParents() == 1 {
+			var err error
+			if parent, err = c.Parent(0); err != nil {
+				return err
+			}
+		}
+		changed, err := changedFiles(parent, c, retries)
+		if err != nil {
+			return err
+		}
+		for file, changeType := range changed {
+			if t, ok := net[file]; ok {
+				files[file] = t
+			} else if _, ok := files[file]; !ok {
+				files[file] = changeType
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
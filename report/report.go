@@ -0,0 +1,230 @@
+// Package report determines the files changed on a branch and contains the
+// ownership report computed by codeownerreport, so that Go programs can
+// consume the same structure the CLI serializes.
+package report
+
+import (
+	"cmp"
+	"encoding/json"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// Report describes who owns the files of a change.
+type Report struct {
+	// Meta describes what the report covers. It is only filled in on
+	// request.
+	Meta *Meta `json:"meta,omitempty"`
+	// Owners maps each owner to the sorted list of files it owns.
+	Owners map[string][]string `json:"owners"`
+	// Unowned lists the files no owner was found for, sorted.
+	Unowned []string `json:"unowned"`
+	// Stats summarizes the report.
+	Stats Stats `json:"stats"`
+	// Files lists every file with its owners, sorted by path. It is only
+	// filled in on request.
+	Files []FileOwnership `json:"files,omitempty"`
+	// Sections maps GitLab CODEOWNERS sections to the owners of files
+	// matched in them and those files. A file matched in several sections
+	// needs approval in each. It is only filled in for GitLab syntax.
+	Sections map[string]map[string][]string `json:"sections,omitempty"`
+	// Changes maps every file to how it was changed, where known.
+	Changes map[string]ChangeType `json:"changes,omitempty"`
+	// Links maps every file to its URL on the hosting provider. It is only
+	// filled in on request.
+	Links map[string]string `json:"links,omitempty"`
+	// Matches describes the rule each file matched. It is only filled in on
+	// request.
+	Matches map[string]Match `json:"matches,omitempty"`
+	// AncestorOwners maps owners of rules that matched a file but were
+	// overridden by a later rule to the files concerned. These owners aren't
+	// required to review, but may want to be notified. It is only filled in
+	// on request.
+	AncestorOwners map[string][]string `json:"ancestor_owners,omitempty"`
+	// CatchAll lists the owned files that only matched a catch-all "*"
+	// rule. It is only filled in on request.
+	CatchAll []string `json:"catch_all,omitempty"`
+	// RuleCoverage lists the rules that matched at least one file, by
+	// descending number of files. It is only filled in on request.
+	RuleCoverage []RuleCoverage `json:"rule_coverage,omitempty"`
+	// ManifestCoverage lists, for every pattern of a coverage manifest, the
+	// changed files matching it and which of those are unowned. It is only
+	// filled in on request.
+	ManifestCoverage []ManifestEntry `json:"manifest_coverage,omitempty"`
+	// Reviewers suggests a single owner to review each owned file, balancing
+	// the load across owners. It is only filled in on request.
+	Reviewers map[string]string `json:"reviewers,omitempty"`
+	// Suggestions lists edits to CODEOWNERS that fix likely mistakes. It is
+	// only filled in on request.
+	Suggestions []Suggestion `json:"suggestions,omitempty"`
+	// Violations lists the files that failed a policy check.
+	Violations []Violation `json:"violations,omitempty"`
+	// Delta describes the changes compared to a previous report. It is only
+	// filled in on request.
+	Delta *Delta `json:"delta,omitempty"`
+}
+
+// Meta describes the change a report covers and when it was generated.
+type Meta struct {
+	Branch      string    `json:"branch,omitempty"`
+	Base        string    `json:"base,omitempty"`
+	BaseCommit  string    `json:"base_commit,omitempty"`
+	HeadCommit  string    `json:"head_commit,omitempty"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// NewMeta describes change, generated now. Times are in UTC with second
+// precision, so they serialize as plain RFC 3339.
+func NewMeta(change Change) Meta {
+	meta := Meta{
+		Branch:      change.CurrentBranch,
+		Base:        change.BaseName,
+		GeneratedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	if change.BaseCommit != nil {
+		meta.BaseCommit = change.BaseCommit.Hash.String()
+	}
+	if change.HeadCommit != nil {
+		meta.HeadCommit = change.HeadCommit.Hash.String()
+	}
+	return meta
+}
+
+// Identifiers of the policy checks a Violation can originate from.
+const (
+	RuleMissingCodeowner = "missing-codeowner"
+	RuleRequiredOwner    = "required-owner"
+	RuleUnknownOwner     = "unknown-owner"
+	RuleCoverageManifest = "coverage-manifest"
+	RuleDirCoverage      = "dir-coverage"
+)
+
+// Identifiers of the analyses a Suggestion can originate from.
+const (
+	SuggestionDuplicate  = "duplicate"
+	SuggestionUnused     = "unused"
+	SuggestionOverridden = "overridden"
+	SuggestionUnownedDir = "unowned-dir"
+)
+
+// Suggestion is an edit to the CODEOWNERS file.
+type Suggestion struct {
+	// Kind identifies the analysis, e.g. SuggestionDuplicate.
+	Kind string `json:"kind"`
+	// Action is the edit to make: add or remove.
+	Action string `json:"action"`
+	// Pattern is the pattern of the rule to add or remove.
+	Pattern string `json:"pattern"`
+	// Lines are the lines to remove.
+	Lines []int `json:"lines,omitempty"`
+	// Section is the GitLab section of the rule, if any.
+	Section string `json:"section,omitempty"`
+	Message string `json:"message"`
+}
+
+// Violation is a file failing a policy check.
+type Violation struct {
+	// Rule identifies the check, e.g. RuleRequiredOwner.
+	Rule    string `json:"rule"`
+	File    string `json:"file"`
+	Message string `json:"message"`
+}
+
+// Match describes which CODEOWNERS rule a file matched.
+type Match struct {
+	// Line is the line number of the rule, or 0 if no rule matched.
+	Line int `json:"line,omitempty"`
+	// Pattern is the pattern of the rule.
+	Pattern string `json:"pattern,omitempty"`
+	// Reason explains why an unowned file has no owners.
+	Reason string `json:"reason,omitempty"`
+}
+
+// ManifestEntry is the coverage of a pattern that files must be owned in.
+type ManifestEntry struct {
+	Pattern string   `json:"pattern"`
+	Files   int      `json:"files"`
+	Unowned []string `json:"unowned"`
+}
+
+// RuleCoverage is a CODEOWNERS rule and the number of files it matched.
+type RuleCoverage struct {
+	Line    int    `json:"line"`
+	Pattern string `json:"pattern"`
+	Files   int    `json:"files"`
+}
+
+// Stats holds aggregate numbers about a Report.
+type Stats struct {
+	Files        int `json:"files"`
+	OwnedFiles   int `json:"owned_files"`
+	UnownedFiles int `json:"unowned_files"`
+	Owners       int `json:"owners"`
+	// SuppressedUnowned counts unowned files left out of Unowned because
+	// they are intentionally unowned.
+	SuppressedUnowned int `json:"suppressed_unowned"`
+	// MultiOwnerFiles lists the files with more than one owner, which need
+	// the most approvals, by descending number of owners.
+	MultiOwnerFiles []MultiOwnerFile `json:"multi_owner_files,omitempty"`
+}
+
+// MultiOwnerFile is a file with more than one owner.
+type MultiOwnerFile struct {
+	File   string `json:"file"`
+	Owners int    `json:"owners"`
+}
+
+// New builds a Report from a mapping of files to their owners. Files mapped to
+// no owners are reported as unowned.
+func New(fileOwners map[string][]string) Report {
+	r := Report{
+		Owners:  map[string][]string{},
+		Unowned: []string{},
+	}
+	for file, owners := range fileOwners {
+		if len(owners) == 0 {
+			r.Unowned = append(r.Unowned, file)
+			continue
+		}
+		for _, owner := range lo.Uniq(owners) {
+			r.Owners[owner] = append(r.Owners[owner], file)
+		}
+	}
+	for _, files := range r.Owners {
+		slices.Sort(files)
+	}
+	slices.Sort(r.Unowned)
+
+	r.Stats = Stats{
+		Files:        len(fileOwners),
+		OwnedFiles:   len(fileOwners) - len(r.Unowned),
+		UnownedFiles: len(r.Unowned),
+		Owners:       len(r.Owners),
+	}
+	for file, owners := range fileOwners {
+		if n := len(lo.Uniq(owners)); n > 1 {
+			r.Stats.MultiOwnerFiles = append(r.Stats.MultiOwnerFiles, MultiOwnerFile{File: file, Owners: n})
+		}
+	}
+	slices.SortFunc(r.Stats.MultiOwnerFiles, func(a, b MultiOwnerFile) int {
+		return cmp.Or(cmp.Compare(b.Owners, a.Owners), cmp.Compare(a.File, b.File))
+	})
+	return r
+}
+
+// OwnerNames returns the owners of the report in alphabetical order.
+func (r Report) OwnerNames() []string {
+	names := lo.Keys(r.Owners)
+	slices.Sort(names)
+	return names
+}
+
+// WriteJSON serializes the report as indented JSON.
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
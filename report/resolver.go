@@ -0,0 +1,95 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/hmarr/codeowners"
+	"github.com/samber/lo"
+)
+
+// OwnerResolver determines the owners of a file. Implement it to take
+// ownership from a source other than CODEOWNERS, such as a service catalog,
+// while reusing the change detection and the report:
+//
+//	type catalog struct{ services map[string][]string }
+//
+//	func (c catalog) Resolve(path string) ([]string, error) {
+//		service, _, _ := strings.Cut(path, "/")
+//		return c.services[service], nil
+//	}
+//
+//	change, err := report.LoadChange(".", report.ChangeOptions{})
+//	...
+//	r, err := report.Resolve(change, catalog{services})
+type OwnerResolver interface {
+	// Resolve returns the owners of the file at path, relative to the
+	// repository root, or none if it is unowned.
+	Resolve(path string) ([]string, error)
+}
+
+// CodeownersResolver resolves owners using the last matching rule of a
+// CODEOWNERS ruleset, like GitHub does.
+type CodeownersResolver struct {
+	Ruleset codeowners.Ruleset
+}
+
+// Resolve implements OwnerResolver.
+func (c CodeownersResolver) Resolve(path string) ([]string, error) {
+	rule, err := c.Ruleset.Match(path)
+	if err != nil || rule == nil {
+		return nil, err
+	}
+	return lo.Map(rule.Owners, func(owner codeowners.Owner, _ int) string {
+		return owner.String()
+	}), nil
+}
+
+// Resolve builds the report for the files of change, looking up their owners
+// with resolver.
+func Resolve(change Change, resolver OwnerResolver) (Report, error) {
+	fileOwners := map[string][]string{}
+	for file := range change.Files {
+		owners, err := resolver.Resolve(file)
+		if err != nil {
+			return Report{}, fmt.Errorf("resolving owners of %s: %w", file, err)
+		}
+		fileOwners[file] = owners
+	}
+	r := New(fileOwners)
+	r.Changes = change.Files
+	return r, nil
+}
+
+// FileOwnership holds the owners of a single file, as emitted by Stream.
+type FileOwnership struct {
+	File   string     `json:"file"`
+	Change ChangeType `json:"change,omitempty"`
+	Owners []string   `json:"owners"`
+}
+
+// Stream resolves the owners of the files of change one at a time, in path
+// order, and passes each result to emit as soon as it is known. Unlike
+// Resolve, no results are kept, so memory stays bounded for huge changes.
+// Stream stops at the first error from resolver or emit, or when ctx is done.
+func Stream(ctx context.Context, change Change, resolver OwnerResolver, emit func(FileOwnership) error) error {
+	files := lo.Keys(change.Files)
+	slices.Sort(files)
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		owners, err := resolver.Resolve(file)
+		if err != nil {
+			return fmt.Errorf("resolving owners of %s: %w", file, err)
+		}
+		if owners == nil {
+			owners = []string{}
+		}
+		if err := emit(FileOwnership{File: file, Change: change.Files[file], Owners: owners}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
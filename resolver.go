@@ -0,0 +1,60 @@
+package main
+
+import (
+	"github.com/hmarr/codeowners"
+	"github.com/samber/lo"
+)
+
+// sectionsResolver is the report.OwnerResolver of the command line. It
+// matches paths as prepared by matchPath against every section, merging the
+// owners of their matching rules, and records the rule each file matched
+// last and, with GitLab syntax, the owners of each section. Nothing is
+// recorded after discard, to keep memory bounded when streaming.
+type sectionsResolver struct {
+	sections       []section
+	opts           options
+	canonicalOwner func(string) string
+
+	rules         map[string]*codeowners.Rule
+	sectionOwners map[string]map[string][]string
+}
+
+// newSectionsResolver creates a resolver reporting owners as canonicalOwner
+// names them, or as written if it is nil.
+func newSectionsResolver(sections []section, opts options, canonicalOwner func(string) string) *sectionsResolver {
+	if canonicalOwner == nil {
+		canonicalOwner = func(owner string) string { return owner }
+	}
+	return &sectionsResolver{
+		sections:       sections,
+		opts:           opts,
+		canonicalOwner: canonicalOwner,
+		rules:          map[string]*codeowners.Rule{},
+		sectionOwners:  map[string]map[string][]string{},
+	}
+}
+
+// Resolve implements report.OwnerResolver.
+func (s *sectionsResolver) Resolve(file string) ([]string, error) {
+	names, rules, err := matchSections(s.sections, matchPath(file, s.opts))
+	if err != nil || len(rules) == 0 {
+		return nil, err
+	}
+	if s.rules != nil {
+		s.rules[file] = rules[len(rules)-1]
+	}
+	owners := lo.Map(rules, func(rule *codeowners.Rule, _ int) []string {
+		return lo.Uniq(lo.Map(rule.Owners, func(owner codeowners.Owner, _ int) string {
+			return s.canonicalOwner(owner.String())
+		}))
+	})
+	if s.opts.gitlab && s.sectionOwners != nil {
+		addSectionOwners(s.sectionOwners, file, names, owners)
+	}
+	return lo.Uniq(lo.Flatten(owners)), nil
+}
+
+// discard stops recording the matched rules and section owners.
+func (s *sectionsResolver) discard() {
+	s.rules, s.sectionOwners = nil, nil
+}
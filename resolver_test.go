@@ -0,0 +1,39 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"codeownerreport/report"
+)
+
+func TestSectionsResolver(t *testing.T) {
+	content := []byte("[Docs] @docs\ndocs/\n[Code] @coders\n*.go\n^[Optional]\n* @Any\n")
+	opts := options{gitlab: true, caseInsensitive: true, pathPrefix: "svc"}
+	_, sections, err := loadRuleset(content, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := newSectionsResolver(sections, opts, nil)
+
+	r, err := report.Resolve(report.Change{Files: map[string]report.ChangeType{
+		"svc/Docs/a.md": report.Added,
+		"svc/main.go":   report.Modified,
+	}}, resolver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileOwners := r.FileOwners()
+	if got, want := fileOwners["svc/Docs/a.md"], []string{"@Any", "@docs"}; !slices.Equal(got, want) {
+		t.Errorf("owners of svc/Docs/a.md = %v, want %v", got, want)
+	}
+	if got, want := fileOwners["svc/main.go"], []string{"@Any", "@coders"}; !slices.Equal(got, want) {
+		t.Errorf("owners of svc/main.go = %v, want %v", got, want)
+	}
+	if got := resolver.sectionOwners["Docs"]["@docs"]; !slices.Equal(got, []string{"svc/Docs/a.md"}) {
+		t.Errorf("Docs section of @docs = %v, want [svc/Docs/a.md]", got)
+	}
+	if rule := resolver.rules["svc/main.go"]; rule == nil || rule.RawPattern() != "*" {
+		t.Errorf("last rule matching svc/main.go = %v, want *", rule)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"codeownerreport/report"
+
+	"github.com/hmarr/codeowners"
+	"github.com/samber/lo"
+)
+
+func parseRuleset(content []byte, opts options) (codeowners.Ruleset, error) {
+	if opts.caseInsensitive {
+		content = mapPatterns(content, strings.ToLower)
+	}
+	var lines []int
+	if opts.expandBraces {
+		content, lines = expandBraceRules(content)
+	}
+
+	ruleset, err := codeowners.ParseFile(bytes.NewReader(content))
+	if err != nil || lines == nil {
+		return ruleset, err
+	}
+	for i := range ruleset {
+		ruleset[i].LineNumber = lines[ruleset[i].LineNumber-1]
+	}
+	return ruleset, nil
+}
+
+// matchPath prepares a changed file's path for matching against the ruleset.
+// Patterns expect clean paths relative to the repository root, so leading
+// "./" and "/" are removed; otherwise anchored patterns like "/docs/" would
+// silently miss them.
+func matchPath(file string, opts options) string {
+	if opts.normalizePaths {
+		file = strings.ReplaceAll(file, `\`, "/")
+	}
+	file = strings.TrimPrefix(path.Clean(file), "/")
+	if opts.pathPrefix != "" {
+		file = strings.TrimPrefix(file, opts.pathPrefix+"/")
+	}
+	if opts.caseInsensitive {
+		file = strings.ToLower(file)
+	}
+	return file
+}
+
+// describeMatches describes the rule each file matched, explaining for unowned
+// files whether no rule matched or the matching rule has no owners.
+func describeMatches(files []string, fileRules map[string]*codeowners.Rule) map[string]report.Match {
+	matches := map[string]report.Match{}
+	for _, file := range files {
+		rule := fileRules[file]
+		switch {
+		case rule == nil:
+			matches[file] = report.Match{Reason: "no matching rule"}
+		case len(rule.Owners) == 0:
+			matches[file] = report.Match{
+				Line:    rule.LineNumber,
+				Pattern: rule.RawPattern(),
+				Reason:  fmt.Sprintf("matched line %d with no owners", rule.LineNumber),
+			}
+		default:
+			matches[file] = report.Match{Line: rule.LineNumber, Pattern: rule.RawPattern()}
+		}
+	}
+	return matches
+}
+
+// ruleCoverage counts the files each rule matched, returning the rules by
+// descending count and by line for equal counts.
+func ruleCoverage(fileRules map[string]*codeowners.Rule) []report.RuleCoverage {
+	counts := map[int]*report.RuleCoverage{}
+	for _, rule := range fileRules {
+		c, ok := counts[rule.LineNumber]
+		if !ok {
+			c = &report.RuleCoverage{Line: rule.LineNumber, Pattern: rule.RawPattern()}
+			counts[rule.LineNumber] = c
+		}
+		c.Files++
+	}
+	coverage := lo.MapToSlice(counts, func(_ int, c *report.RuleCoverage) report.RuleCoverage { return *c })
+	slices.SortFunc(coverage, func(a, b report.RuleCoverage) int {
+		return cmp.Or(cmp.Compare(b.Files, a.Files), cmp.Compare(a.Line, b.Line))
+	})
+	return coverage
+}
+
+// catchAllFiles returns the owned files that match no rule other than a
+// catch-all "*" rule, in sorted order.
+func catchAllFiles(ruleset codeowners.Ruleset, fileRules map[string]*codeowners.Rule, opts options) []string {
+	var files []string
+	for file, rule := range fileRules {
+		if rule.RawPattern() != "*" || len(rule.Owners) == 0 {
+			continue
+		}
+		specific := slices.ContainsFunc(ruleset, func(r codeowners.Rule) bool {
+			match, _ := r.Match(matchPath(file, opts))
+			return match && r.RawPattern() != "*"
+		})
+		if !specific {
+			files = append(files, file)
+		}
+	}
+	slices.Sort(files)
+	return files
+}
+
+// ancestorOwners maps the owners of all rules matching a file besides the
+// winning one to the files concerned. Owners already required by the winning
+// rule are left out.
+func ancestorOwners(ruleset codeowners.Ruleset, fileOwners map[string][]string, fileRules map[string]*codeowners.Rule, opts options, canonicalOwner func(string) string) map[string][]string {
+	ancestors := map[string][]string{}
+	for file, required := range fileOwners {
+		for i := range ruleset {
+			rule := &ruleset[i]
+			if rule == fileRules[file] {
+				continue
+			}
+			if match, _ := rule.Match(matchPath(file, opts)); !match {
+				continue
+			}
+			for _, owner := range rule.Owners {
+				name := canonicalOwner(owner.String())
+				if !slices.Contains(required, name) && !slices.Contains(ancestors[name], file) {
+					ancestors[name] = append(ancestors[name], file)
+				}
+			}
+		}
+	}
+	for _, files := range ancestors {
+		slices.Sort(files)
+	}
+	return ancestors
+}
+
+// ownerCaseFolder returns a function mapping owners that only differ in case
+// to the spelling used first in the ruleset.
+func ownerCaseFolder(ruleset codeowners.Ruleset) func(string) string {
+	spellings := map[string]string{}
+	for _, rule := range ruleset {
+		for _, owner := range rule.Owners {
+			key := strings.ToLower(owner.String())
+			if _, ok := spellings[key]; !ok {
+				spellings[key] = owner.String()
+			}
+		}
+	}
+	return func(owner string) string {
+		if spelling, ok := spellings[strings.ToLower(owner)]; ok {
+			return spelling
+		}
+		return owner
+	}
+}
+
+// parseOwnerRenames parses old=new owner renames. Old names are matched
+// case-insensitively, like GitHub does.
+func parseOwnerRenames(values []string) (map[string]string, error) {
+	renames := map[string]string{}
+	for _, value := range values {
+		old, renamed, ok := strings.Cut(value, "=")
+		old, renamed = strings.TrimSpace(old), strings.TrimSpace(renamed)
+		if !ok || old == "" || renamed == "" {
+			return nil, fmt.Errorf("%q: expected old=new", value)
+		}
+		renames[strings.ToLower(old)] = renamed
+	}
+	return renames, nil
+}
+
+// mapPatterns applies fn to the pattern of every rule in the given CODEOWNERS
+// content. Comments, blank lines, section headers and owners are left
+// untouched, so line numbers stay the same. Escaped characters are kept as
+// they are, too.
+func mapPatterns(content []byte, fn func(string) string) []byte {
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed[0] == '#' || sectionHeader.MatchString(trimmed) {
+			continue
+		}
+		pattern, rest := splitRule(trimmed)
+		lines[i] = mapUnescaped(pattern, fn) + rest
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// mapUnescaped applies fn to the parts of pattern between escaped
+// characters.
+func mapUnescaped(pattern string, fn func(string) string) string {
+	var b strings.Builder
+	start := 0
+	for i := 0; i < len(pattern)-1; i++ {
+		if pattern[i] != '\\' {
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(pattern[i+1:])
+		b.WriteString(fn(pattern[start:i]))
+		b.WriteString(pattern[i : i+1+size])
+		i += size
+		start = i + 1
+	}
+	b.WriteString(fn(pattern[start:]))
+	return b.String()
+}
+
+// splitRule splits a CODEOWNERS rule line at the first unescaped whitespace
+// into its pattern and the remainder (owners and comment).
+func splitRule(line string) (string, string) {
+	escaped := false
+	for i, ch := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case ch == '\\':
+			escaped = true
+		case ch == ' ' || ch == '\t':
+			return line[:i], line[i:]
+		}
+	}
+	return line, ""
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDirectoryPatterns(t *testing.T) {
+	tests := []struct {
+		pattern string
+		file    string
+		match   bool
+	}{
+		// A trailing slash limits the pattern to directories, matching
+		// everything below them.
+		{"docs/", "docs/a.md", true},
+		{"docs/", "docs/guide/a.md", true},
+		{"docs/", "src/docs/a.md", true},
+		{"docs/", "docs", false},
+		{"docs/", "docs.md", false},
+		// Without a trailing slash, files of that name match as well.
+		{"docs", "docs", true},
+		{"docs", "docs/a.md", true},
+		{"docs", "src/docs/a.md", true},
+		{"docs", "docsite/a.md", false},
+		// A leading slash anchors the pattern to the repository root.
+		{"/docs/", "docs/a.md", true},
+		{"/docs/", "docs/guide/a.md", true},
+		{"/docs/", "src/docs/a.md", false},
+		{"/docs", "docs/a.md", true},
+		{"/docs", "src/docs/a.md", false},
+		// A slash inside the pattern anchors it, too.
+		{"src/api/", "src/api/v1/a.go", true},
+		{"src/api/", "lib/src/api/a.go", false},
+		// docs/* only matches direct children.
+		{"docs/*", "docs/a.md", true},
+		{"docs/*", "docs/guide/a.md", false},
+		{"docs/**", "docs/guide/a.md", true},
+		// apps/ matches at any depth.
+		{"apps/", "x/y/apps/z/a.go", true},
+	}
+	for _, tt := range tests {
+		ruleset, err := parseRuleset([]byte(tt.pattern+" @owner\n"), options{})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.pattern, err)
+		}
+		for _, file := range []string{tt.file, "./" + tt.file, "/" + tt.file} {
+			rule, err := ruleset.Match(matchPath(file, options{}))
+			if err != nil {
+				t.Fatalf("%s on %s: %v", tt.pattern, file, err)
+			}
+			if got := rule != nil; got != tt.match {
+				t.Errorf("%s on %s: matched = %v, want %v", tt.pattern, file, got, tt.match)
+			}
+		}
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		file string
+		opts options
+		want string
+	}{
+		{"docs/a.md", options{}, "docs/a.md"},
+		{"./docs//a.md", options{}, "docs/a.md"},
+		{"/docs/a.md", options{}, "docs/a.md"},
+		{`docs\guide\a.md`, options{normalizePaths: true}, "docs/guide/a.md"},
+		{"svc/docs/a.md", options{pathPrefix: "svc"}, "docs/a.md"},
+		{"Docs/A.md", options{caseInsensitive: true}, "docs/a.md"},
+	}
+	for _, tt := range tests {
+		if got := matchPath(tt.file, tt.opts); got != tt.want {
+			t.Errorf("matchPath(%q) = %q, want %q", tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestLoadRulesetEmpty(t *testing.T) {
+	for _, content := range []string{"", "# only comments\n\n"} {
+		for _, gitlab := range []bool{false, true} {
+			ruleset, sections, err := loadRuleset([]byte(content), options{gitlab: gitlab})
+			if err != nil {
+				t.Fatalf("%q (gitlab %v): %v", content, gitlab, err)
+			}
+			if len(ruleset) != 0 {
+				t.Errorf("%q (gitlab %v): got %d rules, want none", content, gitlab, len(ruleset))
+			}
+			names, _, err := matchSections(sections, "src/a.go")
+			if err != nil || len(names) != 0 {
+				t.Errorf("%q (gitlab %v): src/a.go matched %v, %v, want no match", content, gitlab, names, err)
+			}
+		}
+	}
+}
+
+func TestMapPatternsLower(t *testing.T) {
+	content := "# Owners @Org\n[Docs] @Org/Docs\n^[Optional Review]\nDocs/README.md @Org/Docs\nAPI/\\#Notes\\ A.md @Alice # Keep\n"
+	want := "# Owners @Org\n[Docs] @Org/Docs\n^[Optional Review]\ndocs/readme.md @Org/Docs\napi/\\#notes\\ a.md @Alice # Keep\n"
+	if got := string(mapPatterns([]byte(content), strings.ToLower)); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := mapUnescaped(`A\BC\D`, strings.ToLower); got != `a\Bc\D` {
+		t.Errorf("mapUnescaped = %q, want %q", got, `a\Bc\D`)
+	}
+}
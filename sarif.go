@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	"codeownerreport/report"
+)
+
+// SARIF 2.1.0 structures, limited to what the report needs.
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name  string      `json:"name"`
+		Rules []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+)
+
+var sarifRules = []sarifRule{
+	{ID: report.RuleMissingCodeowner, ShortDescription: sarifMessage{Text: "File has no code owner."}},
+	{ID: report.RuleRequiredOwner, ShortDescription: sarifMessage{Text: "File is not owned by its required owner."}},
+	{ID: report.RuleUnknownOwner, ShortDescription: sarifMessage{Text: "File is owned by an unknown owner."}},
+	{ID: report.RuleCoverageManifest, ShortDescription: sarifMessage{Text: "File must be owned according to the coverage manifest."}},
+	{ID: report.RuleDirCoverage, ShortDescription: sarifMessage{Text: "Directory has no rule owning all of its files."}},
+}
+
+// writeSARIF writes unowned files and policy violations as SARIF results, for
+// upload to code scanning.
+func writeSARIF(w io.Writer, r report.Report) error {
+	results := []sarifResult{}
+	failing := map[string]bool{}
+	for _, v := range r.Violations {
+		if v.Rule == report.RuleMissingCodeowner {
+			failing[v.File] = true
+		}
+	}
+	for _, file := range r.Unowned {
+		if !failing[file] {
+			results = append(results, sarifResultFor(report.RuleMissingCodeowner, "warning", file, file+" has no code owner"))
+		}
+	}
+	for _, v := range r.Violations {
+		results = append(results, sarifResultFor(v.Rule, "error", v.File, v.Message))
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: "codeownerreport", Rules: sarifRules}},
+			Results: results,
+		}},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+func sarifResultFor(rule, level, file, message string) sarifResult {
+	return sarifResult{
+		RuleID:  rule,
+		Level:   level,
+		Message: sarifMessage{Text: message},
+		Locations: []sarifLocation{{
+			PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: file}},
+		}},
+	}
+}
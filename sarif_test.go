@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"codeownerreport/report"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	r := report.Report{
+		Unowned: []string{"a.txt", "b.txt"},
+		Violations: []report.Violation{
+			{Rule: report.RuleMissingCodeowner, File: "b.txt", Message: "b.txt has no code owner"},
+			{Rule: report.RuleRequiredOwner, File: "db/1.sql", Message: "db/1.sql must be owned by @org/dba"},
+			{Rule: report.RuleUnknownOwner, File: "c.go", Message: "c.go is owned by unknown owner @gone"},
+			{Rule: report.RuleCoverageManifest, File: "api/d.go", Message: "api/d.go has no code owner but matches api/, which must be owned"},
+			{Rule: report.RuleDirCoverage, File: "web/", Message: "web/ has no rule owning all of its files"},
+		},
+	}
+	var buf bytes.Buffer
+	if err := writeSARIF(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+
+	// Decoded separately from the writer's types, so missing fields show up.
+	var log struct {
+		Schema  *string `json:"$schema"`
+		Version *string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID               string `json:"id"`
+						ShortDescription struct {
+							Text string `json:"text"`
+						} `json:"shortDescription"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID  string `json:"ruleId"`
+				Level   string `json:"level"`
+				Message struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+
+	if log.Schema == nil || *log.Schema == "" {
+		t.Error("$schema is missing")
+	}
+	if log.Version == nil || *log.Version != "2.1.0" {
+		t.Errorf("version = %v, want 2.1.0", log.Version)
+	}
+	if len(log.Runs) != 1 {
+		t.Fatalf("got %d runs, want 1", len(log.Runs))
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name == "" {
+		t.Error("tool.driver.name is missing")
+	}
+	var ruleIDs []string
+	for _, rule := range run.Tool.Driver.Rules {
+		if rule.ID == "" || rule.ShortDescription.Text == "" {
+			t.Errorf("rule %+v lacks an id or description", rule)
+		}
+		ruleIDs = append(ruleIDs, rule.ID)
+	}
+
+	// a.txt is unowned without a violation, b.txt is reported only once.
+	if len(run.Results) != 6 {
+		t.Errorf("got %d results, want 6", len(run.Results))
+	}
+	for _, result := range run.Results {
+		if !slices.Contains(ruleIDs, result.RuleID) {
+			t.Errorf("result rule %q is not declared by the driver, which has %v", result.RuleID, ruleIDs)
+		}
+		if !slices.Contains([]string{"none", "note", "warning", "error"}, result.Level) {
+			t.Errorf("result %s has invalid level %q", result.RuleID, result.Level)
+		}
+		if result.Message.Text == "" {
+			t.Errorf("result %s has no message", result.RuleID)
+		}
+		if len(result.Locations) != 1 || result.Locations[0].PhysicalLocation.ArtifactLocation.URI == "" {
+			t.Errorf("result %s has no artifact location", result.RuleID)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// teams maps team owners to their members. Members may be teams themselves.
+type teams map[string][]string
+
+// loadTeams reads a team mapping file. Every line lists a team followed by its
+// members, separated by whitespace. Empty lines and lines starting with # are
+// ignored. Cyclic mappings are rejected.
+func loadTeams(path string) (teams, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t := teams{}
+	for i, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if _, ok := t[fields[0]]; ok {
+			return nil, fmt.Errorf("line %d: team %s is mapped more than once", i+1, fields[0])
+		}
+		t[fields[0]] = fields[1:]
+	}
+	for _, team := range sortedKeys(t) {
+		if _, err := t.expand(team); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// expand returns the members of owner, recursively replacing teams with their
+// members. Owners that are not mapped are returned as they are.
+func (t teams) expand(owner string) ([]string, error) {
+	var members []string
+	var walk func(owner string, path []string) error
+	walk = func(owner string, path []string) error {
+		if i := slices.Index(path, owner); i >= 0 {
+			return fmt.Errorf("team cycle: %s", strings.Join(append(path[i:], owner), " -> "))
+		}
+		team, ok := t[owner]
+		if !ok {
+			members = append(members, owner)
+			return nil
+		}
+		for _, member := range team {
+			if err := walk(member, append(path, owner)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(owner, nil); err != nil {
+		return nil, err
+	}
+	return lo.Uniq(members), nil
+}
+
+// expandOwners replaces the teams among the owners of every file with their
+// members.
+func (t teams) expandOwners(fileOwners map[string][]string) {
+	for file, owners := range fileOwners {
+		fileOwners[file] = lo.Uniq(lo.FlatMap(owners, func(owner string, _ int) []string {
+			members, _ := t.expand(owner)
+			return members
+		}))
+	}
+}
+
+// memberships returns user and all teams user is a direct or indirect member
+// of, lowercased.
+func (t teams) memberships(user string) map[string]bool {
+	user = strings.ToLower(user)
+	memberships := map[string]bool{user: true}
+	for team := range t {
+		members, _ := t.expand(team)
+		if slices.ContainsFunc(members, func(member string) bool { return strings.EqualFold(member, user) }) {
+			memberships[strings.ToLower(team)] = true
+		}
+	}
+	return memberships
+}
+
+// ownedBy keeps only the files with at least one owner in memberships, and of
+// their owners only those in memberships, so the report lists what the user
+// has to review.
+func ownedBy(fileOwners map[string][]string, memberships map[string]bool) map[string][]string {
+	owned := map[string][]string{}
+	for file, owners := range fileOwners {
+		mine := lo.Filter(owners, func(owner string, _ int) bool { return memberships[strings.ToLower(owner)] })
+		if len(mine) > 0 {
+			owned[file] = mine
+		}
+	}
+	return owned
+}
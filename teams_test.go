@@ -0,0 +1,71 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	tm := teams{
+		"@org/backend": {"@alice", "@org/api"},
+		"@org/api":     {"@bob", "@alice"},
+	}
+	members, err := tm.expand("@org/backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"@alice", "@bob"}; !slices.Equal(members, want) {
+		t.Errorf("expand(@org/backend) = %v, want %v", members, want)
+	}
+	if members, _ := tm.expand("@carol"); !slices.Equal(members, []string{"@carol"}) {
+		t.Errorf("expand(@carol) = %v, want [@carol]", members)
+	}
+}
+
+func TestExpandCycle(t *testing.T) {
+	tm := teams{
+		"a": {"b"},
+		"b": {"@alice", "a"},
+	}
+	_, err := tm.expand("a")
+	if err == nil || err.Error() != "team cycle: a -> b -> a" {
+		t.Errorf("expand(a) = %v, want team cycle: a -> b -> a", err)
+	}
+}
+
+func TestLoadTeamsCycle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "teams")
+	if err := os.WriteFile(path, []byte("a b\nb @alice a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := loadTeams(path)
+	if err == nil || err.Error() != "team cycle: a -> b -> a" {
+		t.Errorf("loadTeams = %v, want team cycle: a -> b -> a", err)
+	}
+}
+
+func TestOwnedBy(t *testing.T) {
+	tm := teams{"@org/api": {"@erin", "@frank"}}
+	fileOwners := map[string][]string{
+		"api/a.go": {"@org/api", "@dave"},
+		"api/b.go": {"@Erin"},
+		"web/c.js": {"@dave"},
+	}
+	owned := ownedBy(fileOwners, tm.memberships("@erin"))
+	want := map[string][]string{
+		"api/a.go": {"@org/api"},
+		"api/b.go": {"@Erin"},
+	}
+	if !maps.EqualFunc(owned, want, slices.Equal) {
+		t.Errorf("ownedBy = %v, want %v", owned, want)
+	}
+
+	tm.expandOwners(fileOwners)
+	owned = ownedBy(fileOwners, tm.memberships("@erin"))
+	if want := []string{"@erin"}; !slices.Equal(owned["api/a.go"], want) {
+		t.Errorf("owners of api/a.go with expanded teams = %v, want %v", owned["api/a.go"], want)
+	}
+}
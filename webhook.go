@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"codeownerreport/report"
+)
+
+// webhookAttempts is how often delivering the report is attempted.
+const webhookAttempts = 3
+
+// parseWebhookHeaders parses headers given as "Name: value".
+func parseWebhookHeaders(values []string) (http.Header, error) {
+	headers := http.Header{}
+	for _, header := range values {
+		name, value, ok := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("%q: expected Name: value", header)
+		}
+		headers.Set(name, strings.TrimSpace(value))
+	}
+	return headers, nil
+}
+
+// postWebhook POSTs the JSON report to url. Network errors and server errors
+// are retried, client errors are not.
+func postWebhook(url string, r report.Report, headers http.Header, timeout time.Duration) error {
+	var body bytes.Buffer
+	if err := r.WriteJSON(&body); err != nil {
+		return err
+	}
+
+	client := http.Client{Timeout: timeout}
+	delay := 500 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		retry, err := deliverWebhook(&client, url, body.Bytes(), headers)
+		if err == nil || !retry || attempt == webhookAttempts {
+			return err
+		}
+		slog.Warn("Retrying webhook.", "attempt", attempt, "error", err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// deliverWebhook makes a single delivery attempt and reports whether a failure
+// is worth retrying.
+func deliverWebhook(client *http.Client, url string, body []byte, headers http.Header) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, values := range headers {
+		req.Header[name] = values
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode >= 500, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return false, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// loadWeights reads a file of owner weights, one owner and weight separated by
+// whitespace per line. Empty lines and lines starting with # are ignored.
+func loadWeights(path string) (map[string]float64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	weights := map[string]float64{}
+	for i, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected an owner and a weight", path, i+1)
+		}
+		weight, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || weight <= 0 {
+			return nil, fmt.Errorf("%s:%d: invalid weight %q", path, i+1, fields[1])
+		}
+		weights[fields[0]] = weight
+	}
+	return weights, nil
+}
+
+// suggestReviewers picks a single reviewer among the owners of every owned
+// file. Each assigned file adds the owner's weight, 1 unless listed, to its
+// load, and files go to the owner whose load would be lowest afterwards, so
+// owners with higher weights get fewer files. Ties are broken by name.
+func suggestReviewers(fileOwners map[string][]string, weights map[string]float64) map[string]string {
+	weight := func(owner string) float64 {
+		if w, ok := weights[owner]; ok {
+			return w
+		}
+		return 1
+	}
+	load := map[string]float64{}
+	reviewers := map[string]string{}
+	for _, file := range sortedKeys(fileOwners) {
+		owners := fileOwners[file]
+		if len(owners) == 0 {
+			continue
+		}
+		best := slices.MinFunc(owners, func(a, b string) int {
+			return cmp.Or(cmp.Compare(load[a]+weight(a), load[b]+weight(b)), cmp.Compare(a, b))
+		})
+		load[best] += weight(best)
+		reviewers[file] = best
+	}
+	return reviewers
+}
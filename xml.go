@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+
+	"codeownerreport/report"
+
+	"github.com/samber/lo"
+)
+
+type xmlOwnership struct {
+	XMLName xml.Name   `xml:"ownership"`
+	Owners  []xmlOwner `xml:"owner"`
+	Unowned xmlFiles   `xml:"unowned"`
+	Stats   xmlStats   `xml:"stats"`
+}
+
+type xmlOwner struct {
+	Name  string    `xml:"name,attr"`
+	Files []xmlFile `xml:"file"`
+}
+
+type xmlFiles struct {
+	Files []xmlFile `xml:"file"`
+}
+
+type xmlFile struct {
+	Change string `xml:"change,attr,omitempty"`
+	Path   string `xml:",chardata"`
+}
+
+type xmlStats struct {
+	Files             int `xml:"files,attr"`
+	OwnedFiles        int `xml:"owned_files,attr"`
+	UnownedFiles      int `xml:"unowned_files,attr"`
+	Owners            int `xml:"owners,attr"`
+	SuppressedUnowned int `xml:"suppressed_unowned,attr"`
+
+	MultiOwnerFiles []xmlMultiOwnerFile `xml:"multi_owner_file"`
+}
+
+type xmlMultiOwnerFile struct {
+	File   string `xml:",chardata"`
+	Owners int    `xml:"owners,attr"`
+}
+
+// writeXML writes the report as XML, mirroring the structure of the JSON
+// output.
+func writeXML(w io.Writer, r report.Report, owners []string) error {
+	files := func(files []string) []xmlFile {
+		return lo.Map(files, func(file string, _ int) xmlFile {
+			return xmlFile{Change: string(r.Changes[file]), Path: file}
+		})
+	}
+	doc := xmlOwnership{
+		Unowned: xmlFiles{Files: files(r.Unowned)},
+		Stats: xmlStats{
+			Files:             r.Stats.Files,
+			OwnedFiles:        r.Stats.OwnedFiles,
+			UnownedFiles:      r.Stats.UnownedFiles,
+			Owners:            r.Stats.Owners,
+			SuppressedUnowned: r.Stats.SuppressedUnowned,
+			MultiOwnerFiles:   lo.Map(r.Stats.MultiOwnerFiles, func(f report.MultiOwnerFile, _ int) xmlMultiOwnerFile { return xmlMultiOwnerFile(f) }),
+		},
+	}
+	for _, owner := range owners {
+		doc.Owners = append(doc.Owners, xmlOwner{Name: owner, Files: files(r.Owners[owner])})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+
+	"codeownerreport/report"
+)
+
+func TestWriteXMLRoundTrip(t *testing.T) {
+	r := report.New(map[string][]string{
+		"docs/a&b.md":   {"@docs"},
+		"src/<gen>.go":  {"@org/backend", "@alice"},
+		"odd \"name\"'": nil,
+	})
+	r.Changes = map[string]report.ChangeType{"docs/a&b.md": report.Added, "src/<gen>.go": report.Modified}
+
+	var buf bytes.Buffer
+	if err := writeXML(&buf, r, r.OwnerNames()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Error("output doesn't start with the XML declaration")
+	}
+
+	var doc xmlOwnership
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not well-formed: %v\n%s", err, buf.String())
+	}
+	want := xmlOwnership{
+		XMLName: xml.Name{Local: "ownership"},
+		Owners: []xmlOwner{
+			{Name: "@alice", Files: []xmlFile{{Change: "M", Path: "src/<gen>.go"}}},
+			{Name: "@docs", Files: []xmlFile{{Change: "A", Path: "docs/a&b.md"}}},
+			{Name: "@org/backend", Files: []xmlFile{{Change: "M", Path: "src/<gen>.go"}}},
+		},
+		Unowned: xmlFiles{Files: []xmlFile{{Path: "odd \"name\"'"}}},
+		Stats: xmlStats{
+			Files:           3,
+			OwnedFiles:      2,
+			UnownedFiles:    1,
+			Owners:          3,
+			MultiOwnerFiles: []xmlMultiOwnerFile{{File: "src/<gen>.go", Owners: 2}},
+		},
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("decoded\n%+v\nwant\n%+v", doc, want)
+	}
+}